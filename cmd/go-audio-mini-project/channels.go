@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"go-audio-mini-project/internal/audio"
+	"go-audio-mini-project/internal/config"
+	"go-audio-mini-project/internal/dsp"
+	"go-audio-mini-project/internal/pipeline"
+	"go-audio-mini-project/internal/ringbuffer"
+)
+
+// processChannels decodes every station listed in cfg.Channels from the same
+// IQ stream. The channelizer splits each block into per-station baseband
+// blocks, which are demodulated concurrently and written to one output per
+// station: a WAV file in cfg.ChannelOutputDir, or a null sink. It returns once
+// the stream ends, or ctx is cancelled, and all files are finalized. A channel
+// that fails stops the others.
+func processChannels(ctx context.Context, rb *ringbuffer.RingBuffer[complex64], cfg *config.Config) error {
+	channelizer := dsp.NewChannelizer(cfg.IQSampleRate, cfg.IntermediateRate, cfg.Channels, cfg.FilterTaps, cfg.ChannelFilterCutoff())
+
+	group, groupCtx := pipeline.WithContext(ctx)
+	inputs := make([]chan []complex64, channelizer.Channels())
+	for i := range inputs {
+		inputs[i] = make(chan []complex64, 16)
+		offset := channelizer.Offset(i)
+		name := fmt.Sprintf("channel %+.1f kHz", offset/1000)
+		group.Go(name, func() error { return demodulateChannel(inputs[i], name, offset, cfg) })
+	}
+
+	for {
+		samples, err := rb.ReadContext(groupCtx, cfg.SampleBlockSize)
+		// io.EOF means the buffer is closed and empty, so we can exit the loop.
+		if err == io.EOF {
+			fmt.Fprintln(logs, "Processor: End of stream, exiting.")
+			break
+		} else if err != nil {
+			break
+		}
+		if len(samples) < cfg.SampleBlockSize {
+			continue
+		}
+
+		for i, block := range channelizer.Process(samples) {
+			inputs[i] <- block
+		}
+	}
+
+	for _, in := range inputs {
+		close(in)
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return rb.Err()
+}
+
+// openChannelSink creates the output for the channel at offset Hz, with the
+// configured sample format and channel count.
+func openChannelSink(offset float64, cfg *config.Config) (audio.Sink, error) {
+	if cfg.Output == "null" {
+		return audio.NewNullSink(cfg.OutputSampleRate, cfg.OutputChannels), nil
+	}
+	format, err := audio.ParseSampleFormat(cfg.OutputFormat)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(cfg.ChannelOutputDir, fmt.Sprintf("channel_%+.1fkHz.wav", offset/1000))
+	fmt.Fprintln(logs, "Writing", path)
+	return audio.CreateWAV(path, cfg.OutputSampleRate, cfg.OutputChannels, format)
+}
+
+// demodulateChannel demodulates the baseband blocks of the channel at offset
+// Hz in the configured mode and writes the audio to its output, duplicated
+// onto both channels for stereo output. Squelch events are logged under the
+// channel's name.
+func demodulateChannel(in <-chan []complex64, name string, offset float64, cfg *config.Config) error {
+	sink, err := openChannelSink(offset, cfg)
+	if err != nil {
+		// Drain the channel so the channelizer never blocks on us.
+		for range in {
+		}
+		return err
+	}
+
+	demod := newDemodulator(cfg)
+	mono := newMonoAudio(cfg)
+	squelch := newSquelch(cfg)
+	dynamics := newAudioDynamics(cfg, 1)
+	var frames []float32
+	for block := range in {
+		demodulated := demod.Process(block)
+		if squelch != nil {
+			var events []dsp.SquelchEvent
+			demodulated, events = squelch.Process(block, demodulated)
+			printSquelchEvents(name+": ", events, cfg)
+		}
+		samples := mono.Process(demodulated)
+		dynamics.Process(samples)
+
+		frames = frames[:0]
+		for _, s := range samples {
+			for range sink.Channels() {
+				frames = append(frames, s)
+			}
+		}
+		if err := sink.Write(frames); err != nil {
+			for range in {
+			}
+			sink.Close()
+			return err
+		}
+	}
+	dynamics.printStats(name + ": ")
+	return sink.Close()
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-audio-mini-project/internal/config"
+)
+
+const usage = `Usage: go-audio-mini-project [flags] <input>
+
+Decodes FM broadcast radio from IQ samples. <input> is a raw IQ, WAV or SigMF
+file, "-" for raw IQ on stdin, or rtl_tcp://host:port for a live rtl_tcp server.
+Frequencies accept k, M and G suffixes, e.g. -offset 400k.
+
+Settings are taken from the defaults, then the -preset, then the -config file,
+then the defaults of a different -mode, and finally the other flags.
+
+Flags:
+`
+
+// parseFlags builds the configuration from the preset, config file and other
+// command-line flags in args, and returns it with the input path. Usage and
+// errors are written to output.
+func parseFlags(args []string, output io.Writer) (*config.Config, string, error) {
+	// The preset, config file and mode are the base the other flags apply
+	// to, so find them first with a throwaway configuration.
+	var preset, file string
+	scratch := config.New()
+	fs := newFlagSet(scratch, &preset, &file)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		// Report the error from the real parse below.
+		preset, file = "", ""
+	}
+	var mode string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "mode" {
+			mode = scratch.Mode
+		}
+	})
+
+	cfg := config.New()
+	if preset != "" {
+		var err error
+		if cfg, err = config.Preset(preset); err != nil {
+			return nil, "", err
+		}
+	}
+	if file != "" {
+		if err := cfg.LoadFile(file); err != nil {
+			return nil, "", err
+		}
+	}
+	if mode != "" && mode != cfg.Mode {
+		cfg.SetMode(mode)
+	}
+
+	fs = newFlagSet(cfg, &preset, &file)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprint(output, usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, "", errors.New("expected exactly one input")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, "", err
+	}
+	return cfg, fs.Arg(0), nil
+}
+
+// newFlagSet defines a flag for every setting in cfg, plus the preset and
+// config file flags.
+func newFlagSet(cfg *config.Config, preset, file *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("go-audio-mini-project", flag.ContinueOnError)
+
+	fs.StringVar(preset, "preset", "", fmt.Sprintf("preset to start from: %s (default wfm-eu)", strings.Join(config.Presets(), ", ")))
+	fs.StringVar(file, "config", "", "JSON, TOML or YAML file of settings")
+
+	// Input
+	fs.IntVar(&cfg.IQSampleRate, "rate", cfg.IQSampleRate, "IQ sample rate in Hz (WAV and SigMF files set their own)")
+	fs.StringVar(&cfg.InputFormat, "format", cfg.InputFormat, "raw IQ sample format: cu8, cs8, cs16, cs24, cs32, cf32 or cf64 (default from the file extension)")
+	frequencyVar(fs, &cfg.CenterFrequency, "center-freq", "capture centre frequency in Hz; tunes rtl_tcp input")
+	fs.Float64Var(&cfg.TunerGain, "tuner-gain", cfg.TunerGain, "rtl_tcp tuner gain in dB, 0 for automatic gain")
+	fs.IntVar(&cfg.FrequencyCorrection, "ppm", cfg.FrequencyCorrection, "rtl_tcp frequency correction in ppm")
+	fs.IntVar(&cfg.ChunkSize, "chunk-size", cfg.ChunkSize, "IQ samples read from the input at a time")
+	fs.Float64Var(&cfg.BufferDuration, "buffer", cfg.BufferDuration, "seconds of IQ the ring buffer holds")
+	fs.StringVar(&cfg.OverflowPolicy, "overflow", cfg.OverflowPolicy, fmt.Sprintf("what a full ring buffer does with new IQ: %s", strings.Join(config.OverflowPolicies, ", ")))
+
+	// Tuning and demodulation
+	frequencyVar(fs, &cfg.TuningOffset, "offset", "tuning offset in Hz from the capture centre frequency to the station")
+	fs.Func("channels", "comma-separated tuning offsets in Hz to decode at once in place of -offset, each to its own WAV file (-output wav) or null output", func(s string) error {
+		cfg.Channels = nil
+		for _, field := range strings.Split(s, ",") {
+			offset, err := parseFrequency(field)
+			if err != nil {
+				return err
+			}
+			cfg.Channels = append(cfg.Channels, offset)
+		}
+		return nil
+	})
+	fs.StringVar(&cfg.ChannelOutputDir, "channel-dir", cfg.ChannelOutputDir, "directory for the per-channel WAV files")
+	fs.StringVar(&cfg.Mode, "mode", cfg.Mode, fmt.Sprintf("demodulation mode: %s", strings.Join(config.Modes, ", ")))
+	fs.Func("deemphasis", "de-emphasis time constant, e.g. 50us (Europe), 75us (Americas) or none", func(s string) error {
+		if s == "none" {
+			cfg.DeemphTau = 0
+			return nil
+		}
+		tau, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		cfg.DeemphTau = tau.Seconds()
+		return nil
+	})
+	frequencyVar(fs, &cfg.MaxDeviation, "deviation", "FM deviation in Hz that gives full-scale audio, e.g. 75k (wfm), 5k or 2.5k (nfm)")
+	frequencyVar(fs, &cfg.BFOOffset, "bfo", "BFO offset in Hz added to the pitch of usb, lsb and cw audio")
+	frequencyVar(fs, &cfg.PassbandLow, "passband-low", "low edge of the usb, lsb and cw audio passband in Hz")
+	frequencyVar(fs, &cfg.PassbandHigh, "passband-high", "high edge of the usb, lsb and cw audio passband in Hz")
+	fs.BoolVar(&cfg.Squelch, "squelch", cfg.Squelch, "mute nfm, am and sam audio while no signal is present")
+	fs.Float64Var(&cfg.SquelchOpen, "squelch-open", cfg.SquelchOpen, "level in dB at which the squelch opens: noise quality for nfm, channel power in dBFS for am and sam")
+	fs.Float64Var(&cfg.SquelchClose, "squelch-close", cfg.SquelchClose, "level in dB below which the squelch closes")
+	fs.Float64Var(&cfg.SquelchHang, "squelch-hang", cfg.SquelchHang, "seconds the squelch stays open after the level drops")
+	fs.Float64Var(&cfg.SquelchTail, "squelch-tail", cfg.SquelchTail, "seconds of audio muted before the squelch closes, removing the noise tail")
+	fs.BoolVar(&cfg.RBDS, "rbds", cfg.RBDS, "use North American RBDS programme type names")
+	fs.BoolVar(&cfg.SigMFAnnotate, "sigmf-annotate", cfg.SigMFAnnotate, "write decoded RDS events and squelched transmissions back to a SigMF recording as annotations")
+
+	// Processing
+	fs.IntVar(&cfg.IntermediateRate, "intermediate-rate", cfg.IntermediateRate, "sample rate in Hz after channel filtering")
+	fs.IntVar(&cfg.OutputSampleRate, "output-rate", cfg.OutputSampleRate, "audio sample rate in Hz")
+	fs.IntVar(&cfg.SampleBlockSize, "block-size", cfg.SampleBlockSize, "IQ samples processed per block")
+	fs.IntVar(&cfg.FilterTaps, "taps", cfg.FilterTaps, "taps evaluated per output sample by each resampling filter")
+	frequencyVar(fs, &cfg.ChannelCutoff, "channel-cutoff", "channel filter cutoff in Hz, half the channel bandwidth")
+	frequencyVar(fs, &cfg.AudioCutoff, "audio-cutoff", "audio filter cutoff in Hz")
+
+	// Output
+	fs.BoolVar(&cfg.AGC, "agc", cfg.AGC, "level the audio with the automatic gain control (default on for all modes but wfm)")
+	fs.Float64Var(&cfg.AGCTarget, "agc-target", cfg.AGCTarget, "peak audio level in dBFS the AGC aims for")
+	fs.Float64Var(&cfg.AGCMaxGain, "agc-max-gain", cfg.AGCMaxGain, "most gain in dB the AGC applies")
+	fs.Float64Var(&cfg.AGCAttack, "agc-attack", cfg.AGCAttack, "seconds the AGC takes to follow a rising level")
+	fs.Float64Var(&cfg.AGCRelease, "agc-release", cfg.AGCRelease, "seconds the AGC takes to follow a falling level")
+	fs.Float64Var(&cfg.Gain, "gain", cfg.Gain, "audio gain in dB, applied after the AGC")
+	fs.Float64Var(&cfg.LimiterCeiling, "limiter-ceiling", cfg.LimiterCeiling, "highest audio level in dBFS the limiter lets through")
+	fs.Float64Var(&cfg.LimiterLookahead, "limiter-lookahead", cfg.LimiterLookahead, "seconds the limiter sees ahead, delaying the audio as much")
+	fs.Float64Var(&cfg.LimiterRelease, "limiter-release", cfg.LimiterRelease, "seconds the limiter takes to recover after a peak")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, fmt.Sprintf("audio output: %s", strings.Join(config.Outputs, ", ")))
+	fs.StringVar(&cfg.OutputFile, "output-file", cfg.OutputFile, "file written by the wav output")
+	fs.IntVar(&cfg.OutputChannels, "output-channels", cfg.OutputChannels, "1 for mono or 2 for stereo output")
+	fs.StringVar(&cfg.OutputFormat, "output-format", cfg.OutputFormat, fmt.Sprintf("sample format for wav and pcm output: %s", strings.Join(config.OutputFormats, ", ")))
+	return fs
+}
+
+// frequencyVar defines a flag for a frequency in Hz that accepts k, M and G
+// suffixes.
+func frequencyVar(fs *flag.FlagSet, p *float64, name, usage string) {
+	if *p != 0 {
+		usage += fmt.Sprintf(" (default %g)", *p)
+	}
+	fs.Func(name, usage, func(s string) error {
+		hz, err := parseFrequency(s)
+		if err != nil {
+			return err
+		}
+		*p = hz
+		return nil
+	})
+}
+
+// parseFrequency parses a frequency in Hz, such as "-400k" or "98.5M".
+func parseFrequency(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		scale = 1e3
+	case strings.HasSuffix(s, "M"):
+		scale = 1e6
+	case strings.HasSuffix(s, "G"):
+		scale = 1e9
+	}
+	if scale != 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frequency %q", s)
+	}
+	return v * scale, nil
+}
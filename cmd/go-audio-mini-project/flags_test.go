@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-audio-mini-project/internal/config"
+)
+
+func TestParseFlags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "settings.yaml")
+	if err := os.WriteFile(file, []byte("mode: nfm\nrbds: false\nchannel_cutoff: 5000\naudio_cutoff: 2500\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		wantInput string
+		check     func(c *config.Config) bool
+	}{
+		{"defaults", []string{"capture.iq"}, "capture.iq", func(c *config.Config) bool {
+			return c.Mode == "wfm" && c.DeemphTau == 50e-6 && c.ChannelCutoff == 100_000
+		}},
+		{"stdin", []string{"-format", "cs16", "-"}, "-", func(c *config.Config) bool {
+			return c.InputFormat == "cs16"
+		}},
+		{"frequency suffixes", []string{"-center-freq", "100.1M", "-offset", "-400k", "-deviation", "50k", "-"}, "-", func(c *config.Config) bool {
+			return c.CenterFrequency == 100.1e6 && c.TuningOffset == -400_000 && c.MaxDeviation == 50_000
+		}},
+		{"channel list", []string{"-channels", "250k, -0.1M", "-output", "null", "-"}, "-", func(c *config.Config) bool {
+			return len(c.Channels) == 2 && c.Channels[0] == 250_000 && c.Channels[1] == -100_000
+		}},
+		{"preset", []string{"-preset", "wfm-us", "-"}, "-", func(c *config.Config) bool {
+			return c.DeemphTau == 75e-6 && c.RBDS
+		}},
+		// The file changes the mode, so starts from the nfm defaults rather
+		// than the preset, but its settings win over both.
+		{"config file over preset", []string{"-preset", "wfm-us", "-config", file, "-"}, "-", func(c *config.Config) bool {
+			return c.Mode == "nfm" && !c.RBDS && c.ChannelCutoff == 5_000 && c.AudioCutoff == 2_500
+		}},
+		{"mode over config file", []string{"-config", file, "-mode", "am", "-"}, "-", func(c *config.Config) bool {
+			return c.Mode == "am" && c.ChannelCutoff == 4_000 && c.AudioCutoff == 3_000
+		}},
+		{"mode matching the preset", []string{"-mode", "nfm", "-preset", "nfm-25k", "-"}, "-", func(c *config.Config) bool {
+			return c.Mode == "nfm" && c.ChannelCutoff == 12_500 && c.MaxDeviation == 5_000
+		}},
+		// Flags win however they are ordered against the preset, file and mode.
+		{"flags over everything", []string{"-channel-cutoff", "3k", "-preset", "nfm-25k", "-config", file, "-mode", "am", "-"}, "-", func(c *config.Config) bool {
+			return c.Mode == "am" && c.ChannelCutoff == 3_000
+		}},
+	}
+	for _, tt := range tests {
+		cfg, input, err := parseFlags(tt.args, io.Discard)
+		if err != nil {
+			t.Errorf("%s: parseFlags failed: %v", tt.name, err)
+			continue
+		}
+		if input != tt.wantInput {
+			t.Errorf("%s: expected input %q, got %q", tt.name, tt.wantInput, input)
+		}
+		if !tt.check(cfg) {
+			t.Errorf("%s: unexpected settings: %+v", tt.name, cfg)
+		}
+	}
+}
+
+func TestParseFlags_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no input", []string{"-offset", "100k"}, "exactly one input"},
+		{"two inputs", []string{"a.iq", "b.iq"}, "exactly one input"},
+		{"unknown flag", []string{"-tune", "100k", "-"}, "not defined"},
+		{"bad frequency", []string{"-offset", "100kHz", "-"}, "invalid frequency"},
+		{"bad channel", []string{"-channels", "100k,,200k", "-"}, "invalid frequency"},
+		{"bad de-emphasis", []string{"-deemphasis", "50", "-"}, "missing unit"},
+		{"unknown preset", []string{"-preset", "wfm-mars", "-"}, "unknown preset"},
+		{"missing config file", []string{"-config", filepath.Join(t.TempDir(), "missing.yaml"), "-"}, "missing.yaml"},
+		{"invalid settings", []string{"-offset", "950k", "-"}, "does not fit"},
+		{"offset with channels", []string{"-offset", "100k", "-channels", "200k", "-output", "null", "-"}, "not applied"},
+		{"invalid for mode", []string{"-mode", "nfm", "-deviation", "7k", "-"}, "maximum deviation"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseFlags(tt.args, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -1,181 +1,423 @@
 package main
 
 import (
-	"encoding/binary"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"os"
-
-	"github.com/ebitengine/oto/v3"
-	"github.com/go-audio/audio"
-	"github.com/go-audio/wav"
-
+	"os/signal"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go-audio-mini-project/internal/audio"
 	"go-audio-mini-project/internal/config"
 	"go-audio-mini-project/internal/dsp"
+	"go-audio-mini-project/internal/iq"
+	"go-audio-mini-project/internal/pipeline"
+	"go-audio-mini-project/internal/rds"
 	"go-audio-mini-project/internal/ringbuffer"
+	"go-audio-mini-project/internal/rtltcp"
 )
 
 func main() {
-	// Get default configuration
-	cfg := config.New()
+	cfg, path, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
 
-	fmt.Println("Opening file...")
-	file, err := os.Open("sample2.iq")
-	if err != nil {
-		panic(err)
+	err = run(path, cfg)
+	if errors.Is(err, errInterrupted) {
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		os.Exit(130)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
 	}
-	defer file.Close()
+}
 
-	fmt.Println("Creating ring buffer...")
-	rb := ringbuffer.New(cfg.RingBufferSize)
+// logs receives the progress and status messages. It is stdout unless run
+// sends the audio there, when it is stderr.
+var logs io.Writer = os.Stdout
+
+// errInterrupted is returned by run when a signal stopped decoding before the
+// end of the input.
+var errInterrupted = errors.New("interrupted")
+
+// run decodes the input at path with the given configuration, returning once
+// the input is exhausted and every output is finalized.
+func run(path string, cfg *config.Config) error {
+	// Keep log messages out of a PCM audio stream.
+	logs = os.Stdout
+	if cfg.Output == "pcm" {
+		logs = os.Stderr
+	}
 
-	decoder := wav.NewDecoder(file)
+	// The reader and processor run as stages of a group, so the first to fail
+	// stops the other. The first SIGINT or SIGTERM stops reading the input, so
+	// the rest of the pipeline drains the ring buffer and finalizes its
+	// outputs. A second one cancels everything.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	group, ctx := pipeline.WithContext(ctx)
+	inputCtx, stopInput := context.WithCancel(ctx)
+	defer stopInput()
+	interrupted := handleSignals(stopInput, cancel)
+
+	input, err := openInput(cfg, path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	recording := input.recording
+
+	// The input may have changed the sample rate, so check the result again.
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 
-	fmt.Println("Setting up audio...")
-	// Setup Oto v3 context
-	ctx, ready, err := oto.NewContext(&oto.NewContextOptions{
-		SampleRate:   cfg.OutputSampleRate,
-		ChannelCount: 1,
-		Format:       oto.FormatSignedInt16LE,
-	})
+	fmt.Fprintln(logs, "Creating ring buffer...")
+	policy, err := ringbuffer.ParseOverflowPolicy(cfg.OverflowPolicy)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	<-ready
+	rb := ringbuffer.NewWithPolicy[complex64](cfg.RingBufferSize(), policy)
 
-	reader, writer := io.Pipe()
-	player := ctx.NewPlayer(reader)
-	defer player.Close()
+	var process func() error
+	if len(cfg.Channels) > 0 {
+		// Multi-channel mode writes each station to its own WAV file instead of playing it.
+		fmt.Fprintf(logs, "Decoding %d channels...\n", len(cfg.Channels))
+		process = func() error { return processChannels(ctx, rb, cfg) }
+	} else {
+		sink, err := openSink(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(logs, "Starting processing...")
+		process = func() error { return decode(ctx, rb, sink, input.file, recording, cfg) }
+	}
 
-	go readFileIntoBuffer(file, decoder, rb, cfg)
+	group.Go("reader", func() error { return readIntoBuffer(inputCtx, input, rb, cfg) })
+	group.Go("processor", process)
+	// A file reader waiting for the DSP is normal, but a live source that
+	// fills the buffer is losing samples somewhere.
+	watchOverruns := input.file == nil || policy != ringbuffer.Block
+	done := make(chan struct{})
+	if watchOverruns {
+		go reportOverruns(rb, policy, done)
+	}
+	err = group.Wait()
+	close(done)
+	if watchOverruns {
+		printBufferStats(rb, policy)
+	}
 
-	go player.Play()
+	if interrupted.Load() && (err == nil || errors.Is(err, context.Canceled)) {
+		err = errInterrupted
+	}
+	return err
+}
 
-	fmt.Println("Starting processing...")
-	go processIQ(rb, writer, cfg)
+// handleSignals calls stopInput on the first SIGINT or SIGTERM and cancel on
+// the second. It reports whether a signal has been received.
+func handleSignals(stopInput, cancel context.CancelFunc) *atomic.Bool {
+	var interrupted atomic.Bool
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		interrupted.Store(true)
+		fmt.Fprintln(logs, "[INFO] Stopping: finishing buffered audio (interrupt again to stop now)...")
+		stopInput()
+		<-signals
+		fmt.Fprintln(logs, "[INFO] Stopping now")
+		cancel()
+	}()
+	return &interrupted
+}
 
-	select {} // Block forever
+// input is an open source of raw IQ samples.
+type input struct {
+	io.Reader
+	io.Closer
+	format    iq.Format
+	recording *iq.SigMF // The SigMF recording being read, if any.
+	file      *os.File  // The file being read, if the input is a file.
 }
 
-// Read the file or IO stream into the ring buffer
-// For the file, it may be in a WAV container, so we need to handle that
-func readFileIntoBuffer(file *os.File, decoder *wav.Decoder, rb *ringbuffer.RingBuffer, cfg *config.Config) {
-	defer rb.Close() // Ensure the buffer is closed when this function exits.
-	if !decoder.IsValidFile() {
-		fmt.Println("Not a valid WAV file, reading raw IQ...")
-		buf := make([]byte, cfg.ChunkSize)
-		for {
-			n, err := file.Read(buf)
-			if n > 0 {
-				// Convert the []byte slice to []int16 before writing to the ring buffer.
-				int16Buf := make([]int16, n/2)
-				for i := 0; i < n/2; i++ {
-					int16Buf[i] = int16(binary.LittleEndian.Uint16(buf[i*2 : i*2+2]))
-				}
-				rb.Write(int16Buf)
-			}
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				fmt.Println("File read error:", err)
-				break
-			}
+// openInput opens the IQ source at path: an rtl_tcp server given as
+// "rtl_tcp://host:port", stdin given as "-", a SigMF recording, a WAV file or
+// a raw IQ file. The configuration is updated with whatever the source
+// describes about itself.
+func openInput(cfg *config.Config, path string) (*input, error) {
+	if addr, ok := strings.CutPrefix(path, "rtl_tcp://"); ok {
+		client, err := openRTLTCP(cfg, addr)
+		if err != nil {
+			return nil, err
 		}
+		return &input{Reader: client, Closer: client, format: iq.CU8}, nil
+	}
 
-	} else {
-		fmt.Println("Reading IQ from WAV file...")
-		// Move to start of PCM/IQ data
-		if err := decoder.FwdToPCM(); err != nil {
-			log.Fatal("Failed to seek to PCM data:", err)
+	if path == "-" {
+		format, err := inputFormat(cfg, path)
+		if err != nil {
+			return nil, err
 		}
+		return &input{Reader: os.Stdin, Closer: io.NopCloser(os.Stdin), format: format}, nil
+	}
 
-		// Detect and print the audio format to confirm our assumptions.
-		fmt.Printf("[INFO] Detected WAV format: Bit Depth: %d, Sample Rate: %d, Channels: %d\n",
-			decoder.BitDepth, decoder.SampleRate, decoder.NumChans)
-
-		if decoder.BitDepth != 16 {
-			log.Fatalf("FATAL: This program is hardcoded to process 16-bit audio, but detected %d-bit.", decoder.BitDepth)
+	dataPath := path
+	// A SigMF recording describes its own sample rate, format and centre frequency.
+	var recording *iq.SigMF
+	if iq.IsSigMF(path) {
+		var err error
+		recording, err = iq.OpenSigMF(path)
+		if err != nil {
+			return nil, err
 		}
-
-		// Preallocate reusable buffer for streamed PCM data
-		buf := &audio.IntBuffer{
-			Format: decoder.Format(),
-			Data:   make([]int, cfg.ChunkSize*2), // 2 = I+Q
+		if err := applySigMF(cfg, recording); err != nil {
+			return nil, err
 		}
+		dataPath = recording.DataPath
+	}
 
-		fmt.Println("Adding to ring buffer...")
-		for {
-			n, err := decoder.PCMBuffer(buf)
-			if err == io.EOF {
-				fmt.Println("End of WAV file reached")
-				break
-			}
+	fmt.Fprintln(logs, "Opening file...")
+	file, err := os.Open(dataPath)
+	if err != nil {
+		return nil, err
+	}
+	in := &input{Reader: file, Closer: file, recording: recording, file: file}
+
+	// A WAV header, when present, describes the sample rate and format.
+	header, err := iq.ReadWAVHeader(file)
+	switch {
+	case err == nil:
+		in.Reader, err = applyWAV(cfg, header, file)
+	case errors.Is(err, iq.ErrNotWAV):
+		// Checking for a WAV header consumed part of the file, so start again.
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		in.format, err = inputFormat(cfg, path)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return in, nil
+}
+
+// openRTLTCP connects to an rtl_tcp server and tunes it to the configured
+// centre frequency, sample rate and gain.
+func openRTLTCP(cfg *config.Config, addr string) (*rtltcp.Client, error) {
+	if cfg.CenterFrequency == 0 {
+		return nil, errors.New("rtl_tcp input needs CenterFrequency to be set")
+	}
+	fmt.Fprintf(logs, "Connecting to rtl_tcp at %s...\n", addr)
+	client, err := rtltcp.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	info := client.Info()
+	fmt.Fprintf(logs, "[INFO] rtl_tcp tuner: %s (%d gain settings)\n", info.Tuner, info.GainCount)
+
+	manualGain := cfg.TunerGain != 0
+	err = errors.Join(
+		client.SetSampleRate(cfg.IQSampleRate),
+		client.SetFrequency(cfg.CenterFrequency),
+		client.SetFrequencyCorrection(cfg.FrequencyCorrection),
+		client.SetGainMode(manualGain),
+	)
+	if err == nil && manualGain {
+		err = client.SetGain(cfg.TunerGain)
+	}
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// applySigMF configures the decoder from the metadata of a SigMF recording
+// and prints the annotations it already contains.
+func applySigMF(cfg *config.Config, recording *iq.SigMF) error {
+	format, err := recording.Format()
+	if err != nil {
+		return err
+	}
+	cfg.InputFormat = format.String()
+	if rate := recording.SampleRate(); rate > 0 {
+		cfg.IQSampleRate = rate
+	}
+	cfg.CenterFrequency = recording.CenterFrequency()
+
+	fmt.Fprintf(logs, "[INFO] SigMF recording: %s at %d Hz, centre frequency %.0f Hz\n",
+		format, cfg.IQSampleRate, cfg.CenterFrequency)
+	for _, a := range recording.Annotations {
+		fmt.Fprintf(logs, "[SigMF] Sample %d: %s %s\n", a.SampleStart, a.Label, a.Comment)
+	}
+	return nil
+}
 
-			samples := make([]int16, n)
-			for i := 0; i < n; i += int(decoder.NumChans) {
-				samples[i] = int16(buf.Data[i])
-				samples[i+1] = int16(buf.Data[i+1])
+// applyWAV configures the decoder from the header of an IQ WAV file and
+// returns a reader for its sample data.
+func applyWAV(cfg *config.Config, header *iq.WAVHeader, file io.Reader) (io.Reader, error) {
+	format, err := header.Format()
+	if err != nil {
+		return nil, err
+	}
+	cfg.InputFormat = format.String()
+	cfg.IQSampleRate = header.SampleRate
+	if header.CenterFrequency != 0 {
+		cfg.CenterFrequency = header.CenterFrequency
+	}
+
+	fmt.Fprintf(logs, "[INFO] Detected WAV format: %s, Sample Rate: %d, Channels: %d\n",
+		format, header.SampleRate, header.Channels)
+	if header.CenterFrequency != 0 {
+		fmt.Fprintf(logs, "[INFO] Centre frequency %.0f Hz, recorded %s to %s\n",
+			header.CenterFrequency, header.StartTime.Format(time.DateTime), header.StopTime.Format(time.DateTime))
+	}
+
+	if header.DataSize < 0 {
+		return file, nil
+	}
+	return io.LimitReader(file, header.DataSize), nil
+}
+
+// inputFormat picks the raw IQ sample format from the configuration, or from
+// the file extension when none is configured, defaulting to cs16.
+func inputFormat(cfg *config.Config, path string) (iq.Format, error) {
+	if cfg.InputFormat != "" {
+		return iq.ParseFormat(cfg.InputFormat)
+	}
+	if format, ok := iq.FormatFromPath(path); ok {
+		return format, nil
+	}
+	return iq.CS16, nil
+}
+
+// readIntoBuffer decodes raw IQ samples from the input into the ring buffer,
+// closing it at the end of the input or when ctx is cancelled. A read error
+// is returned and also closes the buffer with it, so the processor can see why
+// the stream ended.
+func readIntoBuffer(ctx context.Context, input *input, rb *ringbuffer.RingBuffer[complex64], cfg *config.Config) error {
+	defer rb.Close() // Ensure the buffer is closed when this function exits.
+
+	// Closing the input unblocks a read waiting on a network stream or pipe.
+	stop := context.AfterFunc(ctx, func() { input.Close() })
+	defer stop()
+
+	fmt.Fprintf(logs, "Reading %s IQ...\n", input.format)
+	reader := iq.NewReader(input.Reader, input.format)
+	samples := make([]complex64, cfg.ChunkSize)
+	for ctx.Err() == nil {
+		n, err := reader.Read(samples)
+		if n > 0 {
+			// Writing gives up if processing stopped early and left the buffer full.
+			if _, err := rb.WriteContext(ctx, samples[:n]); err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return err
 			}
-			rb.Write(samples)
+		}
+		if err == io.EOF {
+			fmt.Fprintln(logs, "End of input reached")
+			return nil
+		} else if err != nil && ctx.Err() == nil {
+			err = fmt.Errorf("reading input: %w", err)
+			rb.CloseWithError(err)
+			return err
 		}
 	}
+	fmt.Fprintln(logs, "Stopped reading input")
+	return nil
 }
 
-func processIQ(rb *ringbuffer.RingBuffer, writer *io.PipeWriter, cfg *config.Config) {
-	frameSize := cfg.SampleBlockSize * 2 // We need two int16 samples (I and Q) per complex sample.
+// processIQ decodes the station and writes the audio to sink, downmixed to
+// mono if the sink has one channel. It returns at the end of the stream, with
+// the error the ring buffer was closed with, or as soon as writing fails or
+// ctx is cancelled. When recording is not nil and annotation is enabled, RDS
+// events and transmissions opened by the squelch are written back to it as
+// SigMF annotations at the end of the stream.
+func processIQ(ctx context.Context, rb *ringbuffer.RingBuffer[complex64], sink audio.Sink, recording *iq.SigMF, cfg *config.Config) error {
+	// --- Stage 0: Tuning ---
+	// Shift the wanted station, TuningOffset Hz away from the capture centre, down to DC.
+	tuner := dsp.NewNCO(cfg.IQSampleRate, -cfg.TuningOffset)
 
 	// --- Stage 1: Channel Selection Filter ---
-	// This filter selects the ~200kHz FM station from the 2MHz SDR stream.
-	channelTaps := dsp.DesignFIRLowPass(cfg.FilterTaps, cfg.ChannelFilterCutoff)
-	channelFilterI := dsp.NewFIRFilter(channelTaps)
-	channelFilterQ := dsp.NewFIRFilter(channelTaps)
-
-	// --- Stage 2: FM Demodulator ---
-	demod := dsp.NewDemodulator()
-
-	// --- Stage 3: Audio Filtering and De-emphasis ---
-	audioTaps := dsp.DesignFIRLowPass(cfg.FilterTaps, cfg.AudioFilterCutoff)
-	audioFilter := dsp.NewFIRFilter(audioTaps)
-	deemph := dsp.NewDeemphasis(cfg.OutputSampleRate, cfg.DeemphTau)
+	// This filter selects the station's channel, ~200kHz for broadcast FM, from
+	// the 2MHz SDR stream and resamples it, by an exact rational factor where
+	// possible (3/25 for 2MHz -> 240kHz). Narrow voice channels are decimated
+	// in stages (2MHz -> 96kHz -> 48kHz) to keep the filter's edges sharp.
+	channelFilterI := newChannelFilter(cfg)
+	channelFilterQ := newChannelFilter(cfg)
+
+	// --- Stage 2: Demodulator for the mode ---
+	demod := newDemodulator(cfg)
+	wideband := cfg.Mode == "wfm"
+
+	// --- Stage 2b: RDS Decoder, fed from the multiplex before audio filtering ---
+	rdsDecoder := rds.NewDecoder(cfg.IntermediateRate, cfg.RBDS)
+
+	// --- Stage 3: Stereo Decoding, Audio Filtering and De-emphasis ---
+	// Broadcast FM carries a stereo multiplex; the other modes are mono.
+	stereo := dsp.NewStereoDecoder(cfg.IntermediateRate, cfg.OutputSampleRate, cfg.FilterTaps, cfg.AudioFilterCutoff(), cfg.DeemphTau)
+	mono := newMonoAudio(cfg)
+	squelch := newSquelch(cfg)
+	var opened dsp.SquelchEvent
+	dynamics := newAudioDynamics(cfg, 2)
 	var blockCounter int64
-	var clippedSamples int64
+	var stereoLocked bool
+	var frames []float32
 
 	for {
 		blockCounter++
-		raw := rb.Read(frameSize)
-		// If Read returns nil, the buffer is closed and empty, so we can exit the loop.
-		if raw == nil {
-			fmt.Println("Processor: End of stream, exiting.")
-			break
+		samples, err := rb.ReadContext(ctx, cfg.SampleBlockSize)
+		// io.EOF means the buffer is closed and empty, so we can exit the loop.
+		if err == io.EOF {
+			fmt.Fprintln(logs, "Processor: End of stream, exiting.")
+			dynamics.printStats("")
+			if recording != nil && cfg.SigMFAnnotate {
+				if err := recording.Save(); err != nil {
+					return errors.Join(rb.Err(), fmt.Errorf("saving SigMF annotations: %w", err))
+				}
+			}
+			return rb.Err()
+		} else if err != nil {
+			return err
 		}
 
-		if len(raw) < frameSize {
+		if len(samples) < cfg.SampleBlockSize {
 			continue
 		}
 
+		// === STAGE 0: Frequency Translation ===
+		tuner.Process(samples)
+
 		I := make([]float32, cfg.SampleBlockSize)
 		Q := make([]float32, cfg.SampleBlockSize)
-
-		for i := 0; i < cfg.SampleBlockSize; i++ {
-			iVal := raw[2*i]
-			qVal := raw[2*i+1]
-			I[i] = float32(iVal) / 32768.0
-			Q[i] = float32(qVal) / 32768.0
+		for i, s := range samples {
+			I[i] = real(s)
+			Q[i] = imag(s)
 		}
 		var preFilterMag float32
 		for i := 0; i < cfg.SampleBlockSize; i++ {
 			preFilterMag += I[i]*I[i] + Q[i]*Q[i]
 		}
 
-		// === STAGE 1: Channel Filtering and Decimation (2MHz -> 240kHz) ===
-		ratioStage1 := float64(cfg.IntermediateRate) / float64(cfg.IQSampleRate)
-		intermediateI := channelFilterI.Process(I, ratioStage1)
-		intermediateQ := channelFilterQ.Process(Q, ratioStage1)
-
-		if intermediateI == nil {
-			continue
-		}
+		// === STAGE 1: Channel Filtering and Decimation (2MHz -> 240kHz for wfm) ===
+		intermediateI := channelFilterI.Process(I)
+		intermediateQ := channelFilterQ.Process(Q)
 
 		// Combine I and Q into complex samples for the new demodulator
 		complexSamples := make([]complex64, len(intermediateI))
@@ -183,38 +425,113 @@ func processIQ(rb *ringbuffer.RingBuffer, writer *io.PipeWriter, cfg *config.Con
 			complexSamples[i] = complex(intermediateI[i], intermediateQ[i])
 		}
 
-		// === STAGE 2: FM Demodulation ===
-		phaseDiffs := demod.Process(complexSamples)
+		// === STAGE 2: Demodulation ===
+		demodulated := demod.Process(complexSamples)
 
-		// === STAGE 3: Audio Filtering and Final Resampling (240kHz -> 48kHz) ===
-		ratioStage2 := float64(cfg.OutputSampleRate) / float64(cfg.IntermediateRate)
-		finalAudioRaw := audioFilter.Process(phaseDiffs, ratioStage2)
-
-		if finalAudioRaw == nil {
-			continue
-		}
+		var left, right []float32
+		if wideband {
+			// === STAGE 2b: RDS Decoding (57kHz subcarrier) ===
+			for _, update := range rdsDecoder.Process(demodulated) {
+				fmt.Fprintf(logs, "[RDS] %s\n", update)
+				if recording != nil && cfg.SigMFAnnotate {
+					recording.Annotate(rdsAnnotation(update, blockCounter-1, cfg))
+				}
+			}
 
-		for i, rawSample := range finalAudioRaw {
-			// The scaling factor here determines the audio volume.
-			audio := deemph.Filter(float64(rawSample)) * 4000.0
+			// === STAGE 3: Stereo Decoding and Final Resampling (240kHz -> 48kHz) ===
+			left, right = stereo.Process(demodulated)
 
-			// Handle clipping
-			if audio > 32767 {
-				clippedSamples++
-				audio = 32767
-			} else if audio < -32768 {
-				clippedSamples++
-				audio = -32768
+			if stereo.Stereo() != stereoLocked {
+				stereoLocked = stereo.Stereo()
+				if stereoLocked {
+					fmt.Fprintln(logs, "[INFO] Stereo pilot detected")
+				} else {
+					fmt.Fprintln(logs, "[INFO] Stereo pilot lost, falling back to mono")
+				}
 			}
-
-			if blockCounter%100 == 0 && i == 1 { // Periodically print clipping stats
-				if clippedSamples > 0 {
-					fmt.Printf("[STATS] Total clipped samples so far: %d\n", clippedSamples)
+		} else {
+			// === STAGE 2b: Squelch, muting the audio between transmissions ===
+			if squelch != nil {
+				var events []dsp.SquelchEvent
+				demodulated, events = squelch.Process(complexSamples, demodulated)
+				printSquelchEvents("", events, cfg)
+				for _, e := range events {
+					if e.Open {
+						opened = e
+					} else if recording != nil && cfg.SigMFAnnotate {
+						recording.Annotate(squelchAnnotation(opened, e, cfg))
+					}
 				}
 			}
-			var buf [2]byte
-			binary.LittleEndian.PutUint16(buf[:], uint16(int16(audio)))
-			_, _ = writer.Write(buf[:])
+
+			// === STAGE 3: Audio Filtering and Final Resampling ===
+			left = mono.Process(demodulated)
+			right = slices.Clone(left)
+		}
+
+		// Level the audio and limit its peaks.
+		dynamics.Process(left, right)
+		if blockCounter%100 == 0 && (cfg.AGC || dynamics.limiter.Stats().Limited > 0) { // Periodically print gain stats
+			dynamics.printStats("")
+		}
+
+		frames = frames[:0]
+		for i := range left {
+			if sink.Channels() == 1 {
+				frames = append(frames, (left[i]+right[i])/2)
+			} else {
+				frames = append(frames, left[i], right[i])
+			}
 		}
+		if err := sink.Write(frames); err != nil {
+			return err
+		}
+	}
+}
+
+// rdsAnnotation describes an RDS update decoded from the given block of IQ
+// samples as a SigMF annotation covering that block and the station's channel.
+func rdsAnnotation(update rds.Update, block int64, cfg *config.Config) iq.SigMFAnnotation {
+	a := iq.SigMFAnnotation{
+		SampleStart: uint64(block) * uint64(cfg.SampleBlockSize),
+		SampleCount: uint64(cfg.SampleBlockSize),
+		Label:       "RDS",
+		Comment:     update.String(),
+		Generator:   "go-audio-mini-project",
+	}
+	if cfg.CenterFrequency != 0 {
+		station := cfg.CenterFrequency + cfg.TuningOffset
+		a.FreqLowerEdge = station - 100_000
+		a.FreqUpperEdge = station + 100_000
+	}
+	return a
+}
+
+// squelchAnnotation describes a transmission, from the squelch opening to it
+// closing, as a SigMF annotation covering the station's channel.
+func squelchAnnotation(open, closed dsp.SquelchEvent, cfg *config.Config) iq.SigMFAnnotation {
+	// Squelch events count samples at the intermediate rate.
+	toIQ := func(sample int64) uint64 {
+		return uint64(sample * int64(cfg.IQSampleRate) / int64(cfg.IntermediateRate))
+	}
+	a := iq.SigMFAnnotation{
+		SampleStart: toIQ(open.Sample),
+		SampleCount: toIQ(closed.Sample) - toIQ(open.Sample),
+		Label:       "Transmission",
+		Comment:     fmt.Sprintf("%s squelch open from %.1f dB", cfg.Mode, open.Level),
+		Generator:   "go-audio-mini-project",
 	}
+	if cfg.CenterFrequency != 0 {
+		station := cfg.CenterFrequency + cfg.TuningOffset
+		a.FreqLowerEdge = station - cfg.ChannelCutoff
+		a.FreqUpperEdge = station + cfg.ChannelCutoff
+	}
+	return a
+}
+
+// audioScale returns the factor applying the configured gain to demodulated
+// audio. The demodulators already give full scale at ±1: FM at the maximum
+// deviation, AM at 100% modulation, and SSB and CW at the signal's own level.
+func audioScale(cfg *config.Config) float64 {
+	return math.Pow(10, cfg.Gain/20)
 }
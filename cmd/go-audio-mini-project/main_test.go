@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"go-audio-mini-project/internal/audio"
+	"go-audio-mini-project/internal/config"
+	"go-audio-mini-project/internal/iq"
+	"go-audio-mini-project/internal/ringbuffer"
+)
+
+// fmCS16 encodes numSamples of a carrier at the given offset from the centre,
+// deviated by a 1 kHz tone, as cs16 IQ at sampleRate.
+func fmCS16(sampleRate, numSamples int, offset, deviation float64) []byte {
+	var b []byte
+	phase := 0.0
+	for i := range numSamples {
+		t := float64(i) / float64(sampleRate)
+		phase += 2 * math.Pi * (offset + deviation*math.Sin(2*math.Pi*1000*t)) / float64(sampleRate)
+		b = binary.LittleEndian.AppendUint16(b, uint16(int16(16000*math.Cos(phase))))
+		b = binary.LittleEndian.AppendUint16(b, uint16(int16(16000*math.Sin(phase))))
+	}
+	return b
+}
+
+func TestProcessIQ_EndToEnd(t *testing.T) {
+	cfg := config.New()
+	cfg.TuningOffset = 300_000
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A quarter of a second of a station 300 kHz above the centre, deviated
+	// two thirds of the way to full scale, in whole blocks.
+	numSamples := cfg.IQSampleRate / 4 / cfg.SampleBlockSize * cfg.SampleBlockSize
+	data := fmCS16(cfg.IQSampleRate, numSamples, cfg.TuningOffset, 50_000)
+	in := &input{Reader: bytes.NewReader(data), Closer: io.NopCloser(nil), format: iq.CS16}
+
+	rb := ringbuffer.New[complex64](cfg.RingBufferSize())
+	sink := audio.NewNullSink(cfg.OutputSampleRate, 2)
+	ctx := context.Background()
+	go readIntoBuffer(ctx, in, rb, cfg)
+	if err := processIQ(ctx, rb, sink, nil, cfg); err != nil {
+		t.Fatalf("Expected a nil error at the end of the input, but got %v", err)
+	}
+
+	// Give or take one for where the resamplers' output samples fall.
+	want := int64(numSamples) * int64(cfg.OutputSampleRate) / int64(cfg.IQSampleRate)
+	if frames := sink.Frames(); frames < want-1 || frames > want+1 {
+		t.Errorf("Expected %d frames, but got %d", want, frames)
+	}
+	// 50 kHz of 75 kHz deviation, a little lower after de-emphasis.
+	if peak := sink.Peak(); peak < 0.5 || peak > 0.7 {
+		t.Errorf("Expected a peak level of about 0.63, but got %f", peak)
+	}
+}
+
+// channelGain returns the gain in dB of the channel filter for cfg at a tone
+// freq Hz from the station.
+func channelGain(cfg *config.Config, freq float64) float64 {
+	input := make([]float32, cfg.IQSampleRate/10)
+	for i := range input {
+		input[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / float64(cfg.IQSampleRate)))
+	}
+	output := newChannelFilter(cfg).Process(input)
+
+	// Skip the filters' start-up.
+	var sum float64
+	tail := output[len(output)/2:]
+	for _, x := range tail {
+		sum += float64(x) * float64(x)
+	}
+	return 10 * math.Log10(2*sum/float64(len(tail)))
+}
+
+func TestChannelFilter_AdjacentChannels(t *testing.T) {
+	defaults := func(mode string) *config.Config {
+		cfg := config.New()
+		cfg.SetMode(mode)
+		return cfg
+	}
+	nfm12k, _ := config.Preset("nfm-12.5k")
+	nfm25k, _ := config.Preset("nfm-25k")
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		edge     float64   // Highest frequency the signal occupies, which must pass.
+		adjacent []float64 // Neighbouring channels, which must be rejected.
+	}{
+		{"am", defaults("am"), 3_000, []float64{8_330, 12_500}},
+		// NFM occupies its deviation plus the audio bandwidth.
+		{"nfm", defaults("nfm"), 5_500, []float64{12_500}},
+		{"nfm-12.5k", nfm12k, 5_500, []float64{12_500}},
+		{"nfm-25k", nfm25k, 8_000, []float64{25_000}},
+	}
+	for _, tt := range tests {
+		if gain := channelGain(tt.cfg, tt.edge); gain < -0.5 {
+			t.Errorf("%s: expected a %g Hz tone to pass, but it was %.1f dB down", tt.name, tt.edge, -gain)
+		}
+		for _, f := range tt.adjacent {
+			if gain := channelGain(tt.cfg, f); gain > -40 {
+				t.Errorf("%s: expected the channel %g Hz away to be 40 dB down, but it was %.1f dB down", tt.name, f, -gain)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+
+	"go-audio-mini-project/internal/config"
+	"go-audio-mini-project/internal/dsp"
+)
+
+// newChannelFilter returns a filter that selects the station's channel from
+// one of the I or Q streams and resamples it to the intermediate rate,
+// decimating in stages when the channel is narrow.
+func newChannelFilter(cfg *config.Config) dsp.Resampler {
+	return dsp.NewMultistageResampler(cfg.IQSampleRate, cfg.IntermediateRate, cfg.FilterTaps, cfg.ChannelFilterCutoff())
+}
+
+// demodulator turns the channel-filtered complex baseband into audio at the
+// intermediate rate or, for wfm, into the FM multiplex. FM is normalized to
+// the maximum deviation, so full deviation gives full scale.
+type demodulator interface {
+	Process(samples []complex64) []float32
+}
+
+// newDemodulator returns the demodulator for cfg.Mode.
+func newDemodulator(cfg *config.Config) demodulator {
+	switch cfg.Mode {
+	case "am":
+		return dsp.NewAMDemodulator(cfg.IntermediateRate, false)
+	case "sam":
+		return dsp.NewAMDemodulator(cfg.IntermediateRate, true)
+	case "usb":
+		return dsp.NewSSBDemodulator(cfg.IntermediateRate, dsp.USB, cfg.PassbandLow, cfg.PassbandHigh, cfg.BFOOffset, passbandTaps(cfg))
+	case "cw":
+		// The BFO offset is the tone's pitch, and the passband is centred on it.
+		return dsp.NewCWDemodulator(cfg.IntermediateRate, cfg.BFOOffset, cfg.PassbandHigh-cfg.PassbandLow, passbandTaps(cfg))
+	case "lsb":
+		return dsp.NewSSBDemodulator(cfg.IntermediateRate, dsp.LSB, cfg.PassbandLow, cfg.PassbandHigh, cfg.BFOOffset, passbandTaps(cfg))
+	}
+	return dsp.NewFMDemodulator(cfg.IntermediateRate, cfg.MaxDeviation)
+}
+
+// newSquelch returns the squelch for cfg.Mode, or nil if squelch is off. NFM
+// is squelched on the noise on the discriminator output, AM on the channel
+// power.
+func newSquelch(cfg *config.Config) *dsp.Squelch {
+	if !cfg.Squelch {
+		return nil
+	}
+	detector := dsp.PowerSquelch
+	if cfg.Mode == "nfm" {
+		detector = dsp.NoiseSquelch
+	}
+	return dsp.NewSquelch(cfg.IntermediateRate, detector, cfg.SquelchOpen, cfg.SquelchClose, cfg.SquelchHang, cfg.SquelchTail)
+}
+
+// printSquelchEvents logs the squelch opening and closing on a channel, with
+// the time into the stream at which it happened.
+func printSquelchEvents(channel string, events []dsp.SquelchEvent, cfg *config.Config) {
+	for _, e := range events {
+		state := "closed"
+		if e.Open {
+			state = "opened"
+		}
+		fmt.Fprintf(logs, "[SQUELCH] %s%s at %.2fs (%.1f dB)\n", channel, state, float64(e.Sample)/float64(cfg.IntermediateRate), e.Level)
+	}
+}
+
+// passbandTransition is the width in Hz of the edges of the SSB and CW
+// passband filter.
+const passbandTransition = 100
+
+// passbandTaps returns the number of taps the SSB and CW passband filter
+// needs for edges passbandTransition wide at the intermediate rate. Validate
+// limits the intermediate rate of these modes, which bounds it.
+func passbandTaps(cfg *config.Config) int {
+	// A Hamming windowed sinc's transition is about 3.3 times the sample
+	// rate divided by the number of taps.
+	return int(3.3*float64(cfg.IntermediateRate)/passbandTransition) | 1
+}
+
+// monoAudio filters demodulated mono audio to the audio bandwidth, resamples
+// it to the output rate and applies de-emphasis.
+type monoAudio struct {
+	filter dsp.Resampler
+	deemph *dsp.Deemphasis
+}
+
+func newMonoAudio(cfg *config.Config) *monoAudio {
+	return &monoAudio{
+		filter: dsp.NewResampler(cfg.IntermediateRate, cfg.OutputSampleRate, cfg.FilterTaps, cfg.AudioFilterCutoff()),
+		deemph: dsp.NewDeemphasis(cfg.OutputSampleRate, cfg.DeemphTau),
+	}
+}
+
+// Process returns the audio for a block of demodulated samples.
+func (m *monoAudio) Process(demodulated []float32) []float32 {
+	samples := m.filter.Process(demodulated)
+	for i, s := range samples {
+		samples[i] = float32(m.deemph.Filter(float64(s)))
+	}
+	return samples
+}
+
+// audioDynamics levels the audio with the AGC, if it is enabled, applies the
+// configured gain and keeps the peaks within the limiter's ceiling, in place
+// of clipping them.
+type audioDynamics struct {
+	agc     *dsp.AGC
+	scale   float64
+	limiter *dsp.Limiter
+}
+
+// newAudioDynamics returns the AGC, gain and limiter for the given number of
+// output channels.
+func newAudioDynamics(cfg *config.Config, channels int) *audioDynamics {
+	d := &audioDynamics{
+		scale:   audioScale(cfg),
+		limiter: dsp.NewLimiter(cfg.OutputSampleRate, channels, cfg.LimiterCeiling, cfg.LimiterLookahead, cfg.LimiterRelease),
+	}
+	if cfg.AGC {
+		d.agc = dsp.NewAGC(cfg.OutputSampleRate, cfg.AGCTarget, cfg.AGCMaxGain, cfg.AGCAttack, cfg.AGCRelease)
+	}
+	return d
+}
+
+// Process applies the dynamics to a block of audio, one slice per channel.
+func (d *audioDynamics) Process(channels ...[]float32) {
+	if d.agc != nil {
+		d.agc.Process(channels...)
+	}
+	for _, channel := range channels {
+		for i, s := range channel {
+			channel[i] = float32(float64(s) * d.scale)
+		}
+	}
+	d.limiter.Process(channels...)
+}
+
+// printStats reports the AGC gain and how hard the limiter has worked, with
+// the given prefix naming the channel.
+func (d *audioDynamics) printStats(prefix string) {
+	stats := d.limiter.Stats()
+	var agc string
+	if d.agc != nil {
+		agc = fmt.Sprintf(", AGC gain %+.1f dB", d.agc.Gain())
+	}
+	fmt.Fprintf(logs, "[STATS] %sLimiter gain reduction %.1f dB (peak %.1f dB), %.2f%% of samples limited%s\n",
+		prefix, stats.GainReduction, stats.PeakGainReduction, 100*float64(stats.Limited)/float64(max(stats.Samples, 1)), agc)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go-audio-mini-project/internal/audio"
+	"go-audio-mini-project/internal/config"
+	"go-audio-mini-project/internal/iq"
+	"go-audio-mini-project/internal/ringbuffer"
+)
+
+// openSink creates the audio output selected by cfg.Output. Cancelling ctx
+// stops audio playback without waiting for it to finish.
+func openSink(ctx context.Context, cfg *config.Config) (audio.Sink, error) {
+	format, err := audio.ParseSampleFormat(cfg.OutputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Output {
+	case "audio":
+		fmt.Fprintln(logs, "Setting up audio...")
+		return newOtoSink(ctx, cfg.OutputSampleRate, cfg.OutputChannels)
+	case "wav":
+		fmt.Fprintf(logs, "Rendering %d channel %s audio to %s...\n", cfg.OutputChannels, format, cfg.OutputFile)
+		return audio.CreateWAV(cfg.OutputFile, cfg.OutputSampleRate, cfg.OutputChannels, format)
+	case "pcm":
+		fmt.Fprintf(logs, "Writing %d channel %s PCM at %d Hz to stdout...\n", cfg.OutputChannels, format, cfg.OutputSampleRate)
+		return audio.NewPCMWriter(os.Stdout, cfg.OutputSampleRate, cfg.OutputChannels, format), nil
+	case "null":
+		fmt.Fprintln(logs, "Discarding audio...")
+		return audio.NewNullSink(cfg.OutputSampleRate, cfg.OutputChannels), nil
+	}
+	return nil, fmt.Errorf("unknown output %q", cfg.Output)
+}
+
+// decode runs the DSP chain from rb into sink and closes it, finalizing the
+// output even when ctx is cancelled. Outputs other than audio playback run as
+// fast as the CPU allows, so for them progress through input, when it is a
+// file, is reported along with the speed.
+func decode(ctx context.Context, rb *ringbuffer.RingBuffer[complex64], sink audio.Sink, input *os.File, recording *iq.SigMF, cfg *config.Config) error {
+	realTime := cfg.Output == "audio"
+	done := make(chan struct{})
+	if input != nil && !realTime {
+		go reportProgress(input, done)
+	}
+
+	start := time.Now()
+	counter := &countingSink{Sink: sink}
+	err := processIQ(ctx, rb, counter, recording, cfg)
+	close(done)
+	err = errors.Join(err, sink.Close())
+	if err != nil || realTime {
+		return err
+	}
+
+	elapsed := time.Since(start)
+	duration := time.Duration(float64(counter.frames) / float64(sink.SampleRate()) * float64(time.Second))
+	fmt.Fprintf(logs, "[INFO] Decoded %s of audio in %s (%.1fx real time)\n",
+		duration.Round(time.Second), elapsed.Round(time.Millisecond), duration.Seconds()/elapsed.Seconds())
+	return nil
+}
+
+// countingSink counts the frames written to a sink.
+type countingSink struct {
+	audio.Sink
+	frames int64
+}
+
+func (s *countingSink) Write(samples []float32) error {
+	s.frames += int64(len(samples) / s.Channels())
+	return s.Sink.Write(samples)
+}
+
+// progressInterval is how often reportProgress prints.
+const progressInterval = 2 * time.Second
+
+// reportProgress prints how far through file the reader has got, from its
+// size and current position, until done is closed.
+func reportProgress(file *os.File, done <-chan struct{}) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return
+	}
+	size := info.Size()
+	start := time.Now()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		pos, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return
+		}
+		fraction := float64(pos) / float64(size)
+		line := fmt.Sprintf("[PROGRESS] %5.1f%% (%.1f of %.1f MB)", 100*fraction, float64(pos)/1e6, float64(size)/1e6)
+		if elapsed := time.Since(start); fraction > 0 {
+			remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+			line += fmt.Sprintf(", %s remaining", remaining.Round(time.Second))
+		}
+		fmt.Fprintln(logs, line)
+	}
+}
+
+// reportOverruns warns, at most every progressInterval, when the ring buffer
+// has filled up since the last check because the DSP is not keeping up with
+// the input, until done is closed.
+func reportOverruns(rb *ringbuffer.RingBuffer[complex64], policy ringbuffer.OverflowPolicy, done <-chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	var last ringbuffer.Stats
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		stats := rb.Stats()
+		if stats.Overruns == last.Overruns {
+			continue
+		}
+		if policy == ringbuffer.Block {
+			fmt.Fprintf(logs, "[STATS] DSP is not keeping up: the reader waited for buffer space %d times\n", stats.Overruns-last.Overruns)
+		} else {
+			fmt.Fprintf(logs, "[STATS] DSP is not keeping up: %d IQ samples dropped (%s)\n", stats.Dropped-last.Dropped, policy)
+		}
+		last = stats
+	}
+}
+
+// printBufferStats summarizes the ring buffer counters if the buffer ever
+// filled up.
+func printBufferStats(rb *ringbuffer.RingBuffer[complex64], policy ringbuffer.OverflowPolicy) {
+	stats := rb.Stats()
+	if stats.Overruns == 0 {
+		return
+	}
+	fmt.Fprintf(logs, "[STATS] Ring buffer: %d overruns, %d of %d IQ samples dropped (%s), %d underruns, peak fill %d samples\n",
+		stats.Overruns, stats.Dropped, stats.Written+stats.Dropped, policy, stats.Underruns, stats.HighWater)
+}
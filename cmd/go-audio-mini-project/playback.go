@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+
+	"go-audio-mini-project/internal/audio"
+)
+
+// otoSink is an audio.Sink playing through the system audio output with oto.
+// Audio is piped to the player as 16-bit PCM.
+type otoSink struct {
+	*audio.PCMWriter
+	ctx    context.Context
+	writer *io.PipeWriter
+	player *oto.Player
+}
+
+// newOtoSink sets up audio playback. Only one can be created per process.
+// Once ctx is cancelled, Close no longer waits for playback to finish.
+func newOtoSink(ctx context.Context, sampleRate, channels int) (*otoSink, error) {
+	otoCtx, ready, err := oto.NewContext(&oto.NewContextOptions{
+		SampleRate:   sampleRate,
+		ChannelCount: channels,
+		Format:       oto.FormatSignedInt16LE,
+	})
+	if err != nil {
+		return nil, err
+	}
+	<-ready
+
+	reader, writer := io.Pipe()
+	player := otoCtx.NewPlayer(reader)
+	player.Play()
+	return &otoSink{
+		PCMWriter: audio.NewPCMWriter(writer, sampleRate, channels, audio.Int16),
+		ctx:       ctx,
+		writer:    writer,
+		player:    player,
+	}, nil
+}
+
+// Close lets the player finish the audio already written, then stops it.
+func (s *otoSink) Close() error {
+	s.writer.Close()
+	for s.player.IsPlaying() && s.ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return s.player.Close()
+}
@@ -3,13 +3,12 @@ module go-audio-mini-project
 go 1.25.1
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/ebitengine/oto/v3 v3.4.0
-	github.com/go-audio/audio v1.0.0
-	github.com/go-audio/wav v1.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/ebitengine/purego v0.9.0 // indirect
-	github.com/go-audio/riff v1.0.0 // indirect
 	golang.org/x/sys v0.36.0 // indirect
 )
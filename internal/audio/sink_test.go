@@ -0,0 +1,36 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	_ Sink = (*WAVWriter)(nil)
+	_ Sink = (*PCMWriter)(nil)
+	_ Sink = (*NullSink)(nil)
+)
+
+func TestPCMWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPCMWriter(&out, 48_000, 2, Int16)
+	if err := w.Write([]float32{1, -1, 0, 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xFF, 0x7F, 0x01, 0x80, 0x00, 0x00, 0xFF, 0x7F}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("Expected %v, got %v", want, out.Bytes())
+	}
+}
+
+func TestNullSink(t *testing.T) {
+	s := NewNullSink(48_000, 2)
+	s.Write([]float32{0.1, -0.7, 0.3, 0.2})
+	s.Write([]float32{0.5, 0.5})
+	if s.Frames() != 3 {
+		t.Errorf("Expected 3 frames, got %d", s.Frames())
+	}
+	if s.Peak() != 0.7 {
+		t.Errorf("Expected peak 0.7, got %f", s.Peak())
+	}
+}
@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// wavHeaderSize is the size of the header written by WAVWriter, including the
+// fact chunk that float WAV files carry.
+const wavHeaderSize = 12 + 8 + 16 + 8 + 4 + 8
+
+// maxWAVData is the largest data chunk a RIFF WAV file can describe.
+const maxWAVData = math.MaxUint32 - wavHeaderSize
+
+// WAVWriter is a Sink writing to a WAV file. The sizes in the header are
+// filled in by Close, so the output must be seekable.
+type WAVWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	channels   int
+	format     SampleFormat
+	dataSize   int64
+	buf        []byte
+}
+
+// CreateWAV creates the file at path and returns a WAVWriter for it, which
+// closes the file when it is closed.
+func CreateWAV(path string, sampleRate, channels int, format SampleFormat) (*WAVWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWAVWriter(file, sampleRate, channels, format)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWAVWriter writes a WAV header to w and returns a writer for audio with
+// the given sample rate, channel count and sample format.
+func NewWAVWriter(w io.WriteSeeker, sampleRate, channels int, format SampleFormat) (*WAVWriter, error) {
+	ww := &WAVWriter{w: w, sampleRate: sampleRate, channels: channels, format: format}
+	if _, err := w.Write(ww.header()); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// header returns the WAV header for the data written so far.
+func (w *WAVWriter) header() []byte {
+	le := binary.LittleEndian
+	tag := uint16(1) // PCM
+	if w.format == Float32 {
+		tag = 3 // IEEE float
+	}
+	blockAlign := w.channels * w.format.Size()
+
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = le.AppendUint32(h, uint32(wavHeaderSize-8+w.dataSize))
+	h = append(h, "WAVE"...)
+
+	h = append(h, "fmt "...)
+	h = le.AppendUint32(h, 16)
+	h = le.AppendUint16(h, tag)
+	h = le.AppendUint16(h, uint16(w.channels))
+	h = le.AppendUint32(h, uint32(w.sampleRate))
+	h = le.AppendUint32(h, uint32(w.sampleRate*blockAlign))
+	h = le.AppendUint16(h, uint16(blockAlign))
+	h = le.AppendUint16(h, uint16(8*w.format.Size()))
+
+	h = append(h, "fact"...)
+	h = le.AppendUint32(h, 4)
+	h = le.AppendUint32(h, uint32(w.dataSize/int64(blockAlign)))
+
+	h = append(h, "data"...)
+	return le.AppendUint32(h, uint32(w.dataSize))
+}
+
+// Write writes interleaved samples, with full scale at ±1. Integer output is
+// clipped to full scale.
+func (w *WAVWriter) Write(samples []float32) error {
+	size := len(samples) * w.format.Size()
+	if w.dataSize+int64(size) > maxWAVData {
+		return errors.New("WAV file would exceed 4 GB")
+	}
+
+	w.buf = w.format.append(w.buf[:0], samples)
+	n, err := w.w.Write(w.buf)
+	w.dataSize += int64(n)
+	return err
+}
+
+// SampleRate returns the sample rate in Hz.
+func (w *WAVWriter) SampleRate() int {
+	return w.sampleRate
+}
+
+// Channels returns the number of interleaved channels.
+func (w *WAVWriter) Channels() int {
+	return w.channels
+}
+
+// Frames returns the number of frames written so far.
+func (w *WAVWriter) Frames() int64 {
+	return w.dataSize / int64(w.channels*w.format.Size())
+}
+
+// Close fills in the sizes in the header, and closes the underlying writer if
+// it is an io.Closer.
+func (w *WAVWriter) Close() error {
+	_, err := w.w.Seek(0, io.SeekStart)
+	if err == nil {
+		_, err = w.w.Write(w.header())
+	}
+	if closer, ok := w.w.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+	return err
+}
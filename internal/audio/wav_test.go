@@ -0,0 +1,66 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-audio-mini-project/internal/iq"
+)
+
+func TestWAVWriter(t *testing.T) {
+	samples := []float32{0.5, -0.5, 1.5, -1.5} // Two stereo frames, the second clipping.
+
+	for _, format := range []SampleFormat{Int16, Float32} {
+		path := filepath.Join(t.TempDir(), "out.wav")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWAVWriter(file, 48_000, 2, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(samples[:2]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(samples[2:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		file, _ = os.Open(path)
+		defer file.Close()
+		header, err := iq.ReadWAVHeader(file)
+		if err != nil {
+			t.Fatalf("%v: reading back header failed: %v", format, err)
+		}
+		if header.SampleRate != 48_000 || header.Channels != 2 || header.Float != (format == Float32) ||
+			header.DataSize != int64(len(samples)*format.Size()) {
+			t.Errorf("%v: unexpected header %+v", format, header)
+		}
+
+		data, _ := io.ReadAll(file)
+		var want []float32
+		var got []float32
+		for i := range samples {
+			if format == Float32 {
+				want = append(want, samples[i])
+				got = append(got, math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+			} else {
+				want = append(want, max(min(samples[i], 1), -1))
+				got = append(got, float32(int16(binary.LittleEndian.Uint16(data[2*i:])))/32767)
+			}
+		}
+		for i := range want {
+			if math.Abs(float64(got[i]-want[i])) > 1e-4 {
+				t.Errorf("%v: sample %d: expected %f, got %f", format, i, want[i], got[i])
+			}
+		}
+	}
+}
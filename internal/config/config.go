@@ -1,31 +1,308 @@
 package config
 
-// Config holds all the configuration parameters for the application.
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Modes lists the supported demodulation modes: wideband FM broadcast,
+// narrowband FM, AM with an envelope detector, AM with a synchronous detector,
+// upper and lower sideband and CW.
+var Modes = []string{"wfm", "nfm", "am", "sam", "usb", "lsb", "cw"}
+
+// modeDefaults sets the rates and bandwidths suited to each mode.
+var modeDefaults = map[string]func(*Config){
+	"wfm": func(c *Config) {
+		c.IntermediateRate = 240_000
+		c.ChannelCutoff = 100_000
+		c.AudioCutoff = 15_000
+		c.DeemphTau = 50e-6
+		c.MaxDeviation = 75_000
+		c.AGC = false
+	},
+	// Narrowband FM voice on a 12.5 kHz channel raster.
+	"nfm": func(c *Config) {
+		c.IntermediateRate = 48_000
+		c.ChannelCutoff = 6_250
+		c.AudioCutoff = 3_000
+		c.DeemphTau = 0
+		c.MaxDeviation = 2_500
+		c.SquelchOpen = 0
+		c.SquelchClose = -5
+		c.AGC = true
+	},
+	// Voice AM, such as VHF airband, which fits 8.33 kHz channels.
+	"am":  amDefaults,
+	"sam": amDefaults,
+	// Voice SSB, as used on HF.
+	"usb": ssbDefaults,
+	"lsb": ssbDefaults,
+	// Morse code, heard as a 700 Hz tone through a 500 Hz filter.
+	"cw": func(c *Config) {
+		c.IntermediateRate = 48_000
+		c.ChannelCutoff = 1_000
+		c.AudioCutoff = 3_000
+		c.DeemphTau = 0
+		c.BFOOffset = 700
+		c.PassbandLow = 450
+		c.PassbandHigh = 950
+		c.AGC = true
+	},
+}
+
+func amDefaults(c *Config) {
+	c.IntermediateRate = 48_000
+	c.ChannelCutoff = 4_000
+	c.AudioCutoff = 3_000
+	c.DeemphTau = 0
+	c.SquelchOpen = -40
+	c.SquelchClose = -45
+	c.AGC = true
+}
+
+func ssbDefaults(c *Config) {
+	c.IntermediateRate = 48_000
+	c.ChannelCutoff = 3_000
+	c.AudioCutoff = 3_000
+	c.DeemphTau = 0
+	c.BFOOffset = 0
+	c.PassbandLow = 300
+	c.PassbandHigh = 2_700
+	c.AGC = true
+}
+
+// sidebandModes are the modes shaped by the passband and BFO offset.
+var sidebandModes = []string{"usb", "lsb", "cw"}
+
+// squelchModes are the modes that can be squelched. NFM uses the noise on the
+// discriminator output, so its thresholds are a signal quality in dB with
+// higher meaning cleaner; AM uses the channel power in dBFS.
+var squelchModes = []string{"nfm", "am", "sam"}
+
+// fmModes are the modes whose audio is scaled by the maximum deviation.
+var fmModes = []string{"wfm", "nfm"}
+
+// Outputs lists the supported audio outputs.
+var Outputs = []string{"audio", "wav", "pcm", "null"}
+
+// OutputFormats lists the supported audio sample formats for file output.
+var OutputFormats = []string{"s16", "f32"}
+
+// OverflowPolicies lists what the ring buffer can do when the DSP falls
+// behind the input: block the reader, drop the newest samples or overwrite the
+// oldest.
+var OverflowPolicies = []string{"block", "drop-newest", "overwrite-oldest"}
+
+// Config holds all the configuration parameters for the application. Settings
+// that depend on the sample rates, such as normalized filter cutoffs, are
+// derived from them by methods rather than stored.
 type Config struct {
-	IQSampleRate        int
-	IntermediateRate    int
-	OutputSampleRate    int
-	SampleBlockSize     int
-	FilterTaps          int
-	RingBufferSize      int
-	ChunkSize           int
-	ChannelFilterCutoff float64
-	AudioFilterCutoff   float64
-	DeemphTau           float64
-}
-
-// New returns a new Config with default values.
+	IQSampleRate        int       `json:"iq_sample_rate" toml:"iq_sample_rate" yaml:"iq_sample_rate"`
+	InputFormat         string    `json:"input_format" toml:"input_format" yaml:"input_format"` // Raw IQ sample format (cu8, cs8, cs16, cs24, cs32, cf32, cf64); empty picks it from the file extension
+	IntermediateRate    int       `json:"intermediate_rate" toml:"intermediate_rate" yaml:"intermediate_rate"`
+	OutputSampleRate    int       `json:"output_sample_rate" toml:"output_sample_rate" yaml:"output_sample_rate"`
+	CenterFrequency     float64   `json:"center_frequency" toml:"center_frequency" yaml:"center_frequency"`             // Capture centre frequency in Hz, when known (e.g. from SigMF metadata)
+	TunerGain           float64   `json:"tuner_gain" toml:"tuner_gain" yaml:"tuner_gain"`                               // Manual rtl_tcp tuner gain in dB; 0 selects automatic gain
+	FrequencyCorrection int       `json:"frequency_correction" toml:"frequency_correction" yaml:"frequency_correction"` // rtl_tcp oscillator correction in ppm
+	TuningOffset        float64   `json:"tuning_offset" toml:"tuning_offset" yaml:"tuning_offset"`                      // Hz from the capture centre frequency to the wanted station
+	Channels            []float64 `json:"channels" toml:"channels" yaml:"channels"`                                     // Tuning offsets in Hz to decode at once, each to its own wav or null output
+	ChannelOutputDir    string    `json:"channel_output_dir" toml:"channel_output_dir" yaml:"channel_output_dir"`
+	SampleBlockSize     int       `json:"sample_block_size" toml:"sample_block_size" yaml:"sample_block_size"`
+	FilterTaps          int       `json:"filter_taps" toml:"filter_taps" yaml:"filter_taps"`                   // Taps evaluated per output sample by each resampling filter
+	BufferDuration      float64   `json:"buffer_duration" toml:"buffer_duration" yaml:"buffer_duration"`       // Seconds of IQ the ring buffer holds
+	ChunkSize           int       `json:"chunk_size" toml:"chunk_size" yaml:"chunk_size"`                      // IQ samples read from the input at a time
+	OverflowPolicy      string    `json:"overflow_policy" toml:"overflow_policy" yaml:"overflow_policy"`       // What a full ring buffer does with new samples, one of OverflowPolicies
+	ChannelCutoff       float64   `json:"channel_cutoff" toml:"channel_cutoff" yaml:"channel_cutoff"`          // Channel filter cutoff in Hz, half the channel bandwidth
+	AudioCutoff         float64   `json:"audio_cutoff" toml:"audio_cutoff" yaml:"audio_cutoff"`                // Audio filter cutoff in Hz
+	DeemphTau           float64   `json:"deemph_tau" toml:"deemph_tau" yaml:"deemph_tau"`                      // De-emphasis time constant in seconds; 0 disables it
+	Mode                string    `json:"mode" toml:"mode" yaml:"mode"`                                        // Demodulation mode, one of Modes
+	MaxDeviation        float64   `json:"max_deviation" toml:"max_deviation" yaml:"max_deviation"`             // FM deviation in Hz that gives full-scale audio
+	BFOOffset           float64   `json:"bfo_offset" toml:"bfo_offset" yaml:"bfo_offset"`                      // Hz added to the pitch of usb, lsb and cw audio
+	PassbandLow         float64   `json:"passband_low" toml:"passband_low" yaml:"passband_low"`                // Low edge of the usb, lsb and cw audio passband in Hz
+	PassbandHigh        float64   `json:"passband_high" toml:"passband_high" yaml:"passband_high"`             // High edge of the usb, lsb and cw audio passband in Hz
+	Squelch             bool      `json:"squelch" toml:"squelch" yaml:"squelch"`                               // Mute nfm, am and sam audio while no signal is present
+	SquelchOpen         float64   `json:"squelch_open" toml:"squelch_open" yaml:"squelch_open"`                // Level in dB at which the squelch opens
+	SquelchClose        float64   `json:"squelch_close" toml:"squelch_close" yaml:"squelch_close"`             // Level in dB below which the squelch closes, at most SquelchOpen
+	SquelchHang         float64   `json:"squelch_hang" toml:"squelch_hang" yaml:"squelch_hang"`                // Seconds the squelch stays open after the level drops
+	SquelchTail         float64   `json:"squelch_tail" toml:"squelch_tail" yaml:"squelch_tail"`                // Seconds of audio muted before the squelch closes, removing the noise tail
+	AGC                 bool      `json:"agc" toml:"agc" yaml:"agc"`                                           // Level the audio with the automatic gain control
+	AGCTarget           float64   `json:"agc_target" toml:"agc_target" yaml:"agc_target"`                      // Peak audio level in dBFS the AGC aims for
+	AGCMaxGain          float64   `json:"agc_max_gain" toml:"agc_max_gain" yaml:"agc_max_gain"`                // Most gain in dB the AGC applies
+	AGCAttack           float64   `json:"agc_attack" toml:"agc_attack" yaml:"agc_attack"`                      // Seconds the AGC takes to follow a rising level
+	AGCRelease          float64   `json:"agc_release" toml:"agc_release" yaml:"agc_release"`                   // Seconds the AGC takes to follow a falling level
+	Gain                float64   `json:"gain" toml:"gain" yaml:"gain"`                                        // Audio gain in dB, applied after the AGC
+	LimiterCeiling      float64   `json:"limiter_ceiling" toml:"limiter_ceiling" yaml:"limiter_ceiling"`       // Highest audio level in dBFS the limiter lets through
+	LimiterLookahead    float64   `json:"limiter_lookahead" toml:"limiter_lookahead" yaml:"limiter_lookahead"` // Seconds the limiter sees ahead, delaying the audio as much
+	LimiterRelease      float64   `json:"limiter_release" toml:"limiter_release" yaml:"limiter_release"`       // Seconds the limiter takes to recover after a peak
+	Output              string    `json:"output" toml:"output" yaml:"output"`                                  // Where the audio goes, one of Outputs
+	OutputFile          string    `json:"output_file" toml:"output_file" yaml:"output_file"`                   // Path written by the wav output
+	OutputChannels      int       `json:"output_channels" toml:"output_channels" yaml:"output_channels"`       // 1 for mono or 2 for stereo output
+	OutputFormat        string    `json:"output_format" toml:"output_format" yaml:"output_format"`             // Sample format for wav and pcm output, one of OutputFormats
+	RBDS                bool      `json:"rbds" toml:"rbds" yaml:"rbds"`                                        // Use North American RBDS programme type names
+	SigMFAnnotate       bool      `json:"sigmf_annotate" toml:"sigmf_annotate" yaml:"sigmf_annotate"`          // Write decoded RDS events and squelched transmissions back to a SigMF recording as annotations
+}
+
+// New returns a new Config with default values, equivalent to the "wfm-eu"
+// preset.
 func New() *Config {
 	return &Config{
 		IQSampleRate:        2_000_000,
+		InputFormat:         "",
 		IntermediateRate:    240_000,
 		OutputSampleRate:    48_000,
+		CenterFrequency:     0,
+		TunerGain:           0,
+		FrequencyCorrection: 0,
+		TuningOffset:        0,
+		Channels:            nil,
+		ChannelOutputDir:    ".",
 		SampleBlockSize:     4096,
 		FilterTaps:          251,
-		RingBufferSize:      2 * 2_000_000 * 2, // 2s of IQ (I+Q)
+		BufferDuration:      2,
 		ChunkSize:           8192,
-		ChannelFilterCutoff: 100000.0 / float64(2_000_000),
-		AudioFilterCutoff:   15000.0 / float64(240_000),
+		OverflowPolicy:      "block",
+		ChannelCutoff:       100_000,
+		AudioCutoff:         15_000,
 		DeemphTau:           50e-6, // 50us for Europe
+		Mode:                "wfm",
+		MaxDeviation:        75_000,
+		BFOOffset:           0,
+		PassbandLow:         300,
+		PassbandHigh:        2_700,
+		Squelch:             false,
+		SquelchOpen:         -40,
+		SquelchClose:        -45,
+		SquelchHang:         0.2,
+		SquelchTail:         0.1,
+		AGC:                 false,
+		AGCTarget:           -10,
+		AGCMaxGain:          30,
+		AGCAttack:           0.01,
+		AGCRelease:          0.5,
+		Gain:                0,
+		LimiterCeiling:      -1,
+		LimiterLookahead:    0.005,
+		LimiterRelease:      0.05,
+		Output:              "audio",
+		OutputFile:          "output.wav",
+		OutputChannels:      2,
+		OutputFormat:        "s16",
+		RBDS:                false,
+		SigMFAnnotate:       false,
+	}
+}
+
+// SetMode selects a demodulation mode, together with the intermediate rate,
+// filter cutoffs and de-emphasis suited to it. Settings that should differ
+// from the mode's defaults must be changed afterwards.
+func (c *Config) SetMode(mode string) {
+	c.Mode = mode
+	if apply, ok := modeDefaults[mode]; ok {
+		apply(c)
+	}
+}
+
+// ChannelFilterCutoff returns the channel filter cutoff normalized to the IQ
+// sample rate.
+func (c *Config) ChannelFilterCutoff() float64 {
+	return c.ChannelCutoff / float64(c.IQSampleRate)
+}
+
+// AudioFilterCutoff returns the audio filter cutoff normalized to the
+// intermediate rate.
+func (c *Config) AudioFilterCutoff() float64 {
+	return c.AudioCutoff / float64(c.IntermediateRate)
+}
+
+// RingBufferSize returns the ring buffer size in complex IQ samples.
+func (c *Config) RingBufferSize() int {
+	return int(c.BufferDuration * float64(c.IQSampleRate))
+}
+
+// wfmMinIntermediateRate is the lowest intermediate rate that holds the
+// whole FM multiplex, up to the RDS subcarrier at 57 kHz ± 2.4 kHz.
+const wfmMinIntermediateRate = 120_000
+
+// sidebandMaxIntermediateRate is the highest intermediate rate for the usb,
+// lsb and cw modes. Their passband filter needs more taps the higher the rate
+// to keep its edges sharp, about 3.2k at this rate.
+const sidebandMaxIntermediateRate = 96_000
+
+// Validate checks that the configuration is consistent, returning an error
+// describing the first problem found.
+func (c *Config) Validate() error {
+	switch {
+	case c.IQSampleRate <= 0:
+		return fmt.Errorf("IQ sample rate must be positive, got %d", c.IQSampleRate)
+	case c.IntermediateRate <= 0 || c.IntermediateRate > c.IQSampleRate:
+		return fmt.Errorf("intermediate rate must be between 1 and the IQ sample rate (%d), got %d", c.IQSampleRate, c.IntermediateRate)
+	case c.OutputSampleRate <= 0 || c.OutputSampleRate > c.IntermediateRate:
+		return fmt.Errorf("output sample rate must be between 1 and the intermediate rate (%d), got %d", c.IntermediateRate, c.OutputSampleRate)
+	case c.Mode == "wfm" && c.IntermediateRate < wfmMinIntermediateRate:
+		return fmt.Errorf("wfm needs an intermediate rate of at least %d to hold the stereo and RDS subcarriers, got %d", wfmMinIntermediateRate, c.IntermediateRate)
+	case slices.Contains(sidebandModes, c.Mode) && c.IntermediateRate > sidebandMaxIntermediateRate:
+		return fmt.Errorf("%s needs an intermediate rate of at most %d to keep its passband filter short, got %d", c.Mode, sidebandMaxIntermediateRate, c.IntermediateRate)
+	case c.SampleBlockSize <= 0:
+		return fmt.Errorf("sample block size must be positive, got %d", c.SampleBlockSize)
+	case c.FilterTaps <= 0:
+		return fmt.Errorf("filter taps must be positive, got %d", c.FilterTaps)
+	case c.ChunkSize <= 0:
+		return fmt.Errorf("chunk size must be positive, got %d", c.ChunkSize)
+	case c.RingBufferSize() <= c.SampleBlockSize:
+		return fmt.Errorf("buffer duration of %gs does not hold one block of %d samples", c.BufferDuration, c.SampleBlockSize)
+	case c.ChannelCutoff <= 0 || c.ChannelCutoff >= float64(c.IntermediateRate)/2:
+		return fmt.Errorf("channel cutoff must be between 0 and half the intermediate rate (%d Hz), got %g Hz", c.IntermediateRate/2, c.ChannelCutoff)
+	case c.AudioCutoff <= 0 || c.AudioCutoff >= float64(c.OutputSampleRate)/2:
+		return fmt.Errorf("audio cutoff must be between 0 and half the output sample rate (%d Hz), got %g Hz", c.OutputSampleRate/2, c.AudioCutoff)
+	case c.DeemphTau < 0:
+		return fmt.Errorf("de-emphasis time constant must not be negative, got %g", c.DeemphTau)
+	case c.FrequencyCorrection < -1000 || c.FrequencyCorrection > 1000:
+		return fmt.Errorf("frequency correction must be within ±1000 ppm, got %d", c.FrequencyCorrection)
+	case slices.Contains(fmModes, c.Mode) && (c.MaxDeviation <= 0 || c.MaxDeviation >= c.ChannelCutoff):
+		return fmt.Errorf("maximum deviation must be between 0 and the channel cutoff (%g Hz), got %g Hz", c.ChannelCutoff, c.MaxDeviation)
+	case slices.Contains(sidebandModes, c.Mode) && (c.PassbandLow < 0 || c.PassbandHigh <= c.PassbandLow):
+		return fmt.Errorf("passband must run upwards from 0 Hz or more, got %g to %g Hz", c.PassbandLow, c.PassbandHigh)
+	case slices.Contains(sidebandModes, c.Mode) && math.Max(math.Abs(c.PassbandLow-c.BFOOffset), math.Abs(c.PassbandHigh-c.BFOOffset)) > c.ChannelCutoff:
+		return fmt.Errorf("passband of %g to %g Hz with a %g Hz BFO offset does not fit in the ±%g Hz channel", c.PassbandLow, c.PassbandHigh, c.BFOOffset, c.ChannelCutoff)
+	case c.Squelch && !slices.Contains(squelchModes, c.Mode):
+		return fmt.Errorf("squelch is only available in %v modes, not %q", squelchModes, c.Mode)
+	case c.Squelch && c.SquelchClose > c.SquelchOpen:
+		return fmt.Errorf("squelch close level must not be above the open level (%g dB), got %g dB", c.SquelchOpen, c.SquelchClose)
+	case c.Squelch && (c.SquelchHang < 0 || c.SquelchTail < 0):
+		return fmt.Errorf("squelch hang and tail times must not be negative, got %gs and %gs", c.SquelchHang, c.SquelchTail)
+	case c.AGC && (c.AGCMaxGain < 0 || c.AGCAttack <= 0 || c.AGCRelease <= 0):
+		return fmt.Errorf("AGC needs a maximum gain of 0 dB or more and positive attack and release times, got %g dB, %gs and %gs", c.AGCMaxGain, c.AGCAttack, c.AGCRelease)
+	case c.LimiterCeiling > 0:
+		return fmt.Errorf("limiter ceiling must be at most 0 dBFS, got %g dBFS", c.LimiterCeiling)
+	case c.LimiterLookahead < 0 || c.LimiterRelease <= 0:
+		return fmt.Errorf("limiter look-ahead must not be negative and release must be positive, got %gs and %gs", c.LimiterLookahead, c.LimiterRelease)
+	case !slices.Contains(OverflowPolicies, c.OverflowPolicy):
+		return fmt.Errorf("unknown overflow policy %q (want one of %v)", c.OverflowPolicy, OverflowPolicies)
+	case !slices.Contains(Modes, c.Mode):
+		return fmt.Errorf("unknown mode %q (want one of %v)", c.Mode, Modes)
+	case !slices.Contains(Outputs, c.Output):
+		return fmt.Errorf("unknown output %q (want one of %v)", c.Output, Outputs)
+	case c.Output == "wav" && c.OutputFile == "":
+		return errors.New("wav output needs an output file")
+	case c.OutputChannels != 1 && c.OutputChannels != 2:
+		return fmt.Errorf("output channels must be 1 or 2, got %d", c.OutputChannels)
+	case !slices.Contains(OutputFormats, c.OutputFormat):
+		return fmt.Errorf("unknown output format %q (want one of %v)", c.OutputFormat, OutputFormats)
+	case len(c.Channels) > 0 && c.Output != "wav" && c.Output != "null":
+		return fmt.Errorf("decoding several channels writes one output per channel, so needs the wav or null output, got %q", c.Output)
+	case len(c.Channels) > 0 && c.TuningOffset != 0:
+		return fmt.Errorf("tuning offset of %g Hz is not applied when decoding several channels; give each channel's offset from the capture centre instead", c.TuningOffset)
+	case c.ChannelOutputDir == "" && len(c.Channels) > 0:
+		return errors.New("channel output directory must be set to decode several channels")
+	}
+
+	nyquist := float64(c.IQSampleRate) / 2
+	for _, offset := range append([]float64{c.TuningOffset}, c.Channels...) {
+		if math.Abs(offset)+c.ChannelCutoff > nyquist {
+			return fmt.Errorf("channel at offset %g Hz does not fit in the ±%g Hz captured by the IQ sample rate", offset, nyquist)
+		}
 	}
+	return nil
 }
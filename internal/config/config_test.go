@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_Valid(t *testing.T) {
+	if err := New().Validate(); err != nil {
+		t.Fatalf("Expected the defaults to be valid, got %v", err)
+	}
+}
+
+func TestConfig_Derived(t *testing.T) {
+	c := New()
+	c.IQSampleRate = 2_048_000
+	if got, want := c.ChannelFilterCutoff(), 100_000.0/2_048_000; got != want {
+		t.Errorf("Expected channel filter cutoff %f, got %f", want, got)
+	}
+	if got, want := c.RingBufferSize(), 2*2_048_000; got != want {
+		t.Errorf("Expected ring buffer size %d, got %d", want, got)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"intermediate above input", func(c *Config) { c.IntermediateRate = 3_000_000 }, "intermediate rate"},
+		{"wfm below multiplex", func(c *Config) { c.IntermediateRate = 96_000 }, "stereo and RDS"},
+		{"channel cutoff above Nyquist", func(c *Config) { c.ChannelCutoff = 150_000 }, "channel cutoff"},
+		{"audio cutoff above Nyquist", func(c *Config) { c.AudioCutoff = 30_000 }, "audio cutoff"},
+		{"offset outside capture", func(c *Config) { c.TuningOffset = 950_000 }, "does not fit"},
+		{"tiny buffer", func(c *Config) { c.BufferDuration = 0.001 }, "buffer duration"},
+		{"channels to audio playback", func(c *Config) { c.Channels = []float64{100_000, 200_000} }, "wav or null output"},
+		{"tuning offset with channels", func(c *Config) { c.Output = "null"; c.Channels = []float64{100_000}; c.TuningOffset = 50_000 }, "not applied"},
+		{"unknown mode", func(c *Config) { c.Mode = "ssb" }, "unknown mode"},
+		{"sideband above intermediate limit", func(c *Config) { c.SetMode("lsb"); c.IntermediateRate = 240_000 }, "at most 96000"},
+		{"passband outside channel", func(c *Config) { c.SetMode("usb"); c.PassbandHigh = 3_500 }, "does not fit"},
+		{"inverted passband", func(c *Config) { c.SetMode("cw"); c.PassbandLow = 1_000 }, "passband must run upwards"},
+		{"deviation wider than channel", func(c *Config) { c.SetMode("nfm"); c.MaxDeviation = 7_000 }, "maximum deviation"},
+		{"squelch on wfm", func(c *Config) { c.Squelch = true }, "squelch is only available"},
+		{"squelch closes above opening", func(c *Config) { c.SetMode("nfm"); c.Squelch = true; c.SquelchClose = 3 }, "squelch close level"},
+		{"AGC without attack", func(c *Config) { c.AGC = true; c.AGCAttack = 0 }, "AGC needs"},
+		{"limiter ceiling above full scale", func(c *Config) { c.LimiterCeiling = 3 }, "limiter ceiling"},
+		{"unknown overflow policy", func(c *Config) { c.OverflowPolicy = "discard" }, "overflow policy"},
+	}
+	for _, tt := range tests {
+		c := New()
+		tt.modify(c)
+		err := c.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestConfig_LoadFile(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"iq_sample_rate": 2048000, "tuning_offset": -400000, "channels": [100000, 200000]}`,
+		"config.toml": "iq_sample_rate = 2048000\ntuning_offset = -400000.0\nchannels = [100000.0, 200000.0]\n",
+		"config.yaml": "iq_sample_rate: 2048000\ntuning_offset: -400000\nchannels: [100000, 200000]\n",
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		c := New()
+		if err := c.LoadFile(path); err != nil {
+			t.Fatalf("%s: LoadFile failed: %v", name, err)
+		}
+		if c.IQSampleRate != 2_048_000 || c.TuningOffset != -400_000 || len(c.Channels) != 2 || c.Channels[1] != 200_000 {
+			t.Errorf("%s: settings not loaded: %+v", name, c)
+		}
+		if c.OutputSampleRate != 48_000 {
+			t.Errorf("%s: expected settings missing from the file to keep their defaults, got output rate %d", name, c.OutputSampleRate)
+		}
+	}
+}
+
+func TestConfig_LoadFile_UnknownSetting(t *testing.T) {
+	files := map[string]string{
+		"typo.json": `{"iq_sampel_rate": 2048000}`,
+		"typo.toml": "iq_sampel_rate = 2048000\n",
+		"typo.yaml": "iq_sampel_rate: 2048000\n",
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := New().LoadFile(path); err == nil {
+			t.Errorf("%s: expected an error for an unknown setting", name)
+		}
+	}
+}
+
+func TestConfig_SetMode(t *testing.T) {
+	c := New()
+	c.SetMode("am")
+	if c.IntermediateRate != 48_000 || c.ChannelCutoff != 4_000 || c.AudioCutoff != 3_000 || c.DeemphTau != 0 {
+		t.Errorf("Expected voice AM bandwidths, got %+v", c)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Expected the am defaults to be valid, got %v", err)
+	}
+
+	for _, mode := range []string{"usb", "lsb", "cw"} {
+		c.SetMode(mode)
+		if err := c.Validate(); err != nil {
+			t.Errorf("Expected the %s defaults to be valid, got %v", mode, err)
+		}
+	}
+
+	// Broadcast FM keeps its dynamics; the voice modes are levelled.
+	if c.SetMode("wfm"); c.AGC {
+		t.Error("Expected the AGC to be off for wfm")
+	}
+	if c.SetMode("nfm"); !c.AGC {
+		t.Error("Expected the AGC to be on for nfm")
+	}
+
+	// A config file that changes the mode starts from that mode's defaults.
+	path := filepath.Join(t.TempDir(), "sam.yaml")
+	if err := os.WriteFile(path, []byte("mode: sam\naudio_cutoff: 2500\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c = New()
+	if err := c.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if c.Mode != "sam" || c.ChannelCutoff != 4_000 || c.AudioCutoff != 2_500 {
+		t.Errorf("Expected sam defaults with the file's audio cutoff, got mode %q cutoffs %g and %g", c.Mode, c.ChannelCutoff, c.AudioCutoff)
+	}
+}
+
+func TestPreset(t *testing.T) {
+	us, err := Preset("wfm-us")
+	if err != nil {
+		t.Fatalf("Preset failed: %v", err)
+	}
+	if us.DeemphTau != 75e-6 || !us.RBDS {
+		t.Errorf("Expected 75us de-emphasis and RBDS, got %g and %v", us.DeemphTau, us.RBDS)
+	}
+	if err := us.Validate(); err != nil {
+		t.Errorf("Expected wfm-us to be valid, got %v", err)
+	}
+
+	nfm, _ := Preset("nfm-12.5k")
+	if nfm.Mode != "nfm" || nfm.ChannelCutoff != 6_250 || nfm.MaxDeviation != 2_500 {
+		t.Errorf("Expected a 12.5kHz nfm channel with 2.5kHz deviation, got mode %q cutoff %g deviation %g", nfm.Mode, nfm.ChannelCutoff, nfm.MaxDeviation)
+	}
+	for _, name := range Presets() {
+		p, _ := Preset(name)
+		if err := p.Validate(); err != nil {
+			t.Errorf("Expected preset %s to be valid, got %v", name, err)
+		}
+	}
+
+	if _, err := Preset("wfm-mars"); err == nil {
+		t.Errorf("Expected an error for an unknown preset")
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads settings from a JSON, TOML or YAML file, chosen by its
+// extension, into c. Settings missing from the file keep their current
+// values, so a file only needs to list what differs from the defaults or a
+// preset. A file that changes the mode starts from the new mode's defaults,
+// as SetMode does. Unknown settings are an error, to catch typos.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Find out whether the file changes the mode before applying it.
+	loaded := *c
+	if err := loaded.decode(path, data); err != nil {
+		return err
+	}
+	if loaded.Mode != c.Mode {
+		c.SetMode(loaded.Mode)
+	}
+	return c.decode(path, data)
+}
+
+// decode applies the settings in data, read from path, to c.
+func (c *Config) decode(path string, data []byte) error {
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	case ".toml":
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(data), c)
+		if undecoded := meta.Undecoded(); err == nil && len(undecoded) > 0 {
+			err = fmt.Errorf("unknown setting %q", undecoded[0].String())
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("%s: unknown config file type %q (want .json, .toml or .yaml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
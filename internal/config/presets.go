@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// presets adjust the defaults for common kinds of station.
+var presets = map[string]func(*Config){
+	// Broadcast FM in Europe: the defaults.
+	"wfm-eu": func(c *Config) {},
+	// Broadcast FM in the Americas, with 75us de-emphasis and RBDS.
+	"wfm-us": func(c *Config) {
+		c.DeemphTau = 75e-6
+		c.RBDS = true
+	},
+	// Narrowband FM voice on a 12.5 kHz channel raster, with 2.5 kHz
+	// deviation.
+	"nfm-12.5k": func(c *Config) {
+		c.SetMode("nfm")
+	},
+	// Narrowband FM voice on a 25 kHz channel raster, with 5 kHz deviation.
+	"nfm-25k": func(c *Config) {
+		c.SetMode("nfm")
+		c.ChannelCutoff = 12_500
+		c.MaxDeviation = 5_000
+	},
+	// VHF airband AM voice, which fits 8.33 kHz channels.
+	"airband-am": func(c *Config) {
+		c.SetMode("am")
+	},
+}
+
+// Presets returns the names of the available presets.
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preset returns the defaults adjusted by the named preset.
+func Preset(name string) (*Config, error) {
+	apply, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (want one of %s)", name, strings.Join(Presets(), ", "))
+	}
+	c := New()
+	apply(c)
+	return c, nil
+}
@@ -0,0 +1,64 @@
+package dsp
+
+import "math"
+
+// AGC is an audio automatic gain control. It follows the peak envelope of the
+// audio, rising at the attack rate and falling at the slower release rate, and
+// sets the gain that brings the envelope to the target level, so weak signals
+// are raised and loud ones lowered without the gain following every syllable.
+// The gain never exceeds the maximum, which keeps silence and noise between
+// transmissions from being raised to full level.
+type AGC struct {
+	target   float64 // Wanted envelope level, linear.
+	maxGain  float64 // Linear.
+	attack   float64 // Envelope coefficient while the level rises.
+	release  float64 // Envelope coefficient while the level falls.
+	envelope float64
+}
+
+// NewAGC creates an AGC for audio sampled at sampleRate. target is the wanted
+// peak level in dBFS and maxGain the most gain in dB it may apply. attack and
+// release are the envelope time constants in seconds.
+func NewAGC(sampleRate int, target, maxGain, attack, release float64) *AGC {
+	fs := float64(sampleRate)
+	t := math.Pow(10, target/20)
+	return &AGC{
+		target:   t,
+		maxGain:  math.Pow(10, maxGain/20),
+		attack:   1 - math.Exp(-1/(attack*fs)),
+		release:  1 - math.Exp(-1/(release*fs)),
+		envelope: t, // Start at unity gain.
+	}
+}
+
+// Process applies the gain to the given channels in place. The channels must
+// be the same length and share one gain, so a stereo image doesn't shift.
+func (a *AGC) Process(channels ...[]float32) {
+	if len(channels) == 0 {
+		return
+	}
+	for i := range channels[0] {
+		var peak float64
+		for _, channel := range channels {
+			peak = max(peak, math.Abs(float64(channel[i])))
+		}
+		if peak > a.envelope {
+			a.envelope += a.attack * (peak - a.envelope)
+		} else {
+			a.envelope += a.release * (peak - a.envelope)
+		}
+
+		gain := a.maxGain
+		if a.envelope*a.maxGain > a.target {
+			gain = a.target / a.envelope
+		}
+		for _, channel := range channels {
+			channel[i] = float32(float64(channel[i]) * gain)
+		}
+	}
+}
+
+// Gain returns the gain currently applied in dB.
+func (a *AGC) Gain() float64 {
+	return 20 * math.Log10(min(a.target/a.envelope, a.maxGain))
+}
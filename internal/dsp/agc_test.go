@@ -0,0 +1,64 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// sineBlock returns numSamples of a sine wave at freq Hz with the given peak
+// amplitude.
+func sineBlock(sampleRate, numSamples int, freq, amplitude float64) []float32 {
+	samples := make([]float32, numSamples)
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+	}
+	return samples
+}
+
+func peak(samples []float32) float64 {
+	var p float64
+	for _, s := range samples {
+		p = max(p, math.Abs(float64(s)))
+	}
+	return p
+}
+
+func TestAGC_Levels(t *testing.T) {
+	const sampleRate = 48000
+	const target = -10.0
+	want := math.Pow(10, target/20)
+
+	for _, level := range []float64{-30, -10, 0} {
+		agc := NewAGC(sampleRate, target, 40, 0.01, 0.2)
+		var output []float32
+		for range 20 {
+			block := sineBlock(sampleRate, sampleRate/10, 1000, math.Pow(10, level/20))
+			agc.Process(block)
+			output = append(output, block...)
+		}
+		// The envelope ripples a little with the sine, so allow 1 dB.
+		if got := peak(output[len(output)/2:]); math.Abs(20*math.Log10(got/want)) > 1 {
+			t.Errorf("%g dBFS input: expected a peak level of %g dBFS, but got %.1f dBFS", level, target, 20*math.Log10(got))
+		}
+	}
+
+	// Silence must not raise the gain beyond the maximum.
+	agc := NewAGC(sampleRate, target, 20, 0.01, 0.2)
+	agc.Process(make([]float32, 2*sampleRate))
+	if got := agc.Gain(); math.Abs(got-20) > 1e-9 {
+		t.Errorf("Expected the maximum gain of 20 dB in silence, but got %f dB", got)
+	}
+}
+
+func TestAGC_LinkedChannels(t *testing.T) {
+	const sampleRate = 48000
+	agc := NewAGC(sampleRate, -10, 40, 0.01, 0.2)
+	left := sineBlock(sampleRate, sampleRate, 1000, 0.5)
+	right := sineBlock(sampleRate, sampleRate, 1000, 0.05)
+	agc.Process(left, right)
+
+	// The channels share one gain, so their balance is unchanged.
+	if ratio := peak(left[sampleRate/2:]) / peak(right[sampleRate/2:]); math.Abs(ratio-10) > 0.01 {
+		t.Errorf("Expected the left channel to stay 10 times the right, but got %f", ratio)
+	}
+}
@@ -0,0 +1,94 @@
+package dsp
+
+import "math"
+
+// AMDemodulator recovers the audio from an amplitude-modulated signal at
+// baseband. The envelope detector takes the magnitude of each sample; the
+// synchronous detector locks a PLL to the carrier and takes the in-phase
+// component, which is less distorted by selective fading and adjacent
+// signals. Either way the detected signal is divided by the average carrier
+// level, which removes the DC of the carrier and makes the output independent
+// of the signal strength: 100% modulation gives audio at ±1.
+type AMDemodulator struct {
+	synchronous bool
+	carrier     float64 // Average detector output, the carrier level.
+	smooth      float64 // Averaging coefficient for the carrier level.
+
+	// Carrier PLL state for synchronous detection.
+	phase   float64 // Oscillator phase in radians.
+	freq    float64 // Oscillator frequency in radians per sample.
+	maxFreq float64 // Limit for the oscillator frequency.
+	alpha   float64 // Proportional loop gain.
+	beta    float64 // Integral loop gain.
+}
+
+// AM demodulator tuning.
+const (
+	amCarrierTau      = 0.05 // Seconds over which the carrier level is averaged.
+	amLoopBandwidth   = 30   // Hz, natural frequency of the carrier PLL.
+	amMaxCarrierShift = 2000 // Hz, how far from DC the carrier PLL may pull.
+)
+
+// NewAMDemodulator creates an AM demodulator for baseband sampled at
+// sampleRate, using synchronous detection if synchronous is true and envelope
+// detection otherwise.
+func NewAMDemodulator(sampleRate int, synchronous bool) *AMDemodulator {
+	fs := float64(sampleRate)
+	wn := 2 * math.Pi * amLoopBandwidth / fs
+	const damping = 0.707
+	return &AMDemodulator{
+		synchronous: synchronous,
+		smooth:      1 - math.Exp(-1/(amCarrierTau*fs)),
+		maxFreq:     2 * math.Pi * amMaxCarrierShift / fs,
+		alpha:       2 * damping * wn,
+		beta:        wn * wn,
+	}
+}
+
+// Process demodulates a block of complex baseband samples into audio.
+func (d *AMDemodulator) Process(samples []complex64) []float32 {
+	output := make([]float32, len(samples))
+	for i, s := range samples {
+		var level float64
+		if d.synchronous {
+			level = d.detectSynchronous(complex128(s))
+		} else {
+			level = math.Hypot(float64(real(s)), float64(imag(s)))
+		}
+
+		if d.carrier == 0 {
+			// Start from the first sample rather than ramping up from silence.
+			d.carrier = level
+		}
+		d.carrier += d.smooth * (level - d.carrier)
+		if d.carrier > 1e-9 {
+			output[i] = float32((level - d.carrier) / d.carrier)
+		}
+	}
+	return output
+}
+
+// detectSynchronous mixes s with the carrier PLL's oscillator, advances the
+// loop and returns the in-phase component.
+func (d *AMDemodulator) detectSynchronous(s complex128) float64 {
+	sin, cos := math.Sincos(d.phase)
+	i := real(s)*cos + imag(s)*sin
+	q := imag(s)*cos - real(s)*sin
+
+	// The phase error is independent of the signal level.
+	e := math.Atan2(q, i)
+	d.freq = max(min(d.freq+d.beta*e, d.maxFreq), -d.maxFreq)
+	d.phase = math.Mod(d.phase+d.freq+d.alpha*e, 2*math.Pi)
+	return i
+}
+
+// CarrierLevel returns the average carrier amplitude.
+func (d *AMDemodulator) CarrierLevel() float64 {
+	return d.carrier
+}
+
+// CarrierOffset returns the frequency of the carrier relative to DC in Hz,
+// as tracked by the synchronous detector.
+func (d *AMDemodulator) CarrierOffset(sampleRate int) float64 {
+	return d.freq * float64(sampleRate) / (2 * math.Pi)
+}
@@ -0,0 +1,47 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// generateAM creates an AM signal at baseband whose carrier, of the given
+// amplitude, sits carrierOffset Hz from DC and is modulated to depth by a
+// tone at toneFreq Hz.
+func generateAM(sampleRate, numSamples int, amplitude, carrierOffset, depth, toneFreq float64) []complex64 {
+	samples := make([]complex64, numSamples)
+	for i := range samples {
+		t := float64(i) / float64(sampleRate)
+		envelope := amplitude * (1 + depth*math.Cos(2*math.Pi*toneFreq*t))
+		samples[i] = complex64(cmplx.Rect(envelope, 2*math.Pi*carrierOffset*t+1))
+	}
+	return samples
+}
+
+func TestAMDemodulator(t *testing.T) {
+	const sampleRate = 48000
+	const depth = 0.5
+	want := depth / math.Sqrt2 // RMS of the recovered tone.
+
+	for _, synchronous := range []bool{false, true} {
+		// The output level must not depend on the signal strength.
+		for _, amplitude := range []float64{1, 0.01} {
+			demod := NewAMDemodulator(sampleRate, synchronous)
+			signal := generateAM(sampleRate, sampleRate, amplitude, 300, depth, 1000)
+
+			var audio []float32
+			for start := 0; start < len(signal); start += 4096 {
+				audio = append(audio, demod.Process(signal[start:min(start+4096, len(signal))])...)
+			}
+			if got := rms(audio[len(audio)/2:]); math.Abs(got-want) > 0.1*want {
+				t.Errorf("synchronous=%v amplitude=%g: expected tone RMS %f, got %f", synchronous, amplitude, want, got)
+			}
+			if synchronous {
+				if offset := demod.CarrierOffset(sampleRate); math.Abs(offset-300) > 5 {
+					t.Errorf("amplitude=%g: expected the PLL to lock 300 Hz from DC, got %f", amplitude, offset)
+				}
+			}
+		}
+	}
+}
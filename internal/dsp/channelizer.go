@@ -0,0 +1,83 @@
+package dsp
+
+import "sync"
+
+// Channelizer splits a wideband IQ stream into several independently tuned,
+// narrowband channels. Each channel is a digital down-converter: an NCO moves
+// the channel to DC and a resampler isolates it at the output rate.
+type Channelizer struct {
+	offsets  []float64
+	channels []*downConverter
+}
+
+type downConverter struct {
+	nco     *NCO
+	filterI Resampler
+	filterQ Resampler
+}
+
+// NewChannelizer creates a channelizer for an IQ stream sampled at sampleRate.
+// offsets lists the centre frequency of each channel in Hz relative to the
+// stream's centre. Each channel is resampled to outputRate by a filter with
+// numTaps taps per output sample and the given cutoff, normalized to
+// sampleRate, decimating in stages for narrow channels.
+func NewChannelizer(sampleRate, outputRate int, offsets []float64, numTaps int, cutoff float64) *Channelizer {
+	c := &Channelizer{
+		offsets:  append([]float64(nil), offsets...),
+		channels: make([]*downConverter, len(offsets)),
+	}
+	for i, offset := range offsets {
+		c.channels[i] = &downConverter{
+			nco:     NewNCO(sampleRate, -offset),
+			filterI: NewMultistageResampler(sampleRate, outputRate, numTaps, cutoff),
+			filterQ: NewMultistageResampler(sampleRate, outputRate, numTaps, cutoff),
+		}
+	}
+	return c
+}
+
+// Channels returns the number of channels.
+func (c *Channelizer) Channels() int {
+	return len(c.channels)
+}
+
+// Offset returns the centre frequency of channel i in Hz.
+func (c *Channelizer) Offset(i int) float64 {
+	return c.offsets[i]
+}
+
+// Process splits a block of IQ samples into one baseband block per channel.
+// The channels are processed concurrently. The input block is not modified.
+func (c *Channelizer) Process(iq []complex64) [][]complex64 {
+	out := make([][]complex64, len(c.channels))
+	var wg sync.WaitGroup
+	for i, ch := range c.channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = ch.process(iq)
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+func (d *downConverter) process(iq []complex64) []complex64 {
+	shifted := d.nco.Process(append([]complex64(nil), iq...))
+
+	I := make([]float32, len(shifted))
+	Q := make([]float32, len(shifted))
+	for i, s := range shifted {
+		I[i] = real(s)
+		Q[i] = imag(s)
+	}
+
+	I = d.filterI.Process(I)
+	Q = d.filterQ.Process(Q)
+
+	out := make([]complex64, len(I))
+	for i := range I {
+		out[i] = complex(I[i], Q[i])
+	}
+	return out
+}
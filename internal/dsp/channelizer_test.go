@@ -0,0 +1,51 @@
+package dsp
+
+import (
+	"math/cmplx"
+	"testing"
+)
+
+// meanPower returns the average power of a complex signal, skipping the
+// filter start-up transient.
+func meanPower(samples []complex64, skip int) float64 {
+	var sum float64
+	for _, s := range samples[skip:] {
+		sum += cmplx.Abs(complex128(s)) * cmplx.Abs(complex128(s))
+	}
+	return sum / float64(len(samples)-skip)
+}
+
+func TestChannelizer_SeparatesStations(t *testing.T) {
+	const sampleRate = 2_000_000
+	const outputRate = 250_000
+	offsets := []float64{-400_000, 200_000, 600_000}
+
+	// Two stations, the second channel is left empty.
+	input := generateTone(sampleRate, 40000, offsets[0])
+	other := generateTone(sampleRate, len(input), offsets[2])
+	for i := range input {
+		input[i] += other[i]
+	}
+
+	c := NewChannelizer(sampleRate, outputRate, offsets, 101, 100_000.0/sampleRate)
+	if c.Channels() != len(offsets) {
+		t.Fatalf("Expected %d channels, got %d", len(offsets), c.Channels())
+	}
+
+	outputs := make([][]complex64, c.Channels())
+	for i := 0; i < len(input); i += 4000 {
+		for ch, block := range c.Process(input[i : i+4000]) {
+			outputs[ch] = append(outputs[ch], block...)
+		}
+	}
+
+	for ch, want := range []float64{1, 0, 1} {
+		got := meanPower(outputs[ch], 100)
+		if want == 1 && got < 0.9 {
+			t.Errorf("Channel %d (%.0f Hz): expected station power near 1, got %f", ch, c.Offset(ch), got)
+		}
+		if want == 0 && got > 1e-3 {
+			t.Errorf("Channel %d (%.0f Hz): expected an empty channel, got power %f", ch, c.Offset(ch), got)
+		}
+	}
+}
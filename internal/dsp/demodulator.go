@@ -1,15 +1,27 @@
 package dsp
 
-import "math/cmplx"
+import (
+	"math"
+	"math/cmplx"
+)
 
 // Demodulator implements a polar discriminator for FM demodulation.
 type Demodulator struct {
-	prev complex64
+	prev  complex64
+	scale float32
 }
 
-// NewDemodulator creates a new FM demodulator.
+// NewDemodulator creates a new FM demodulator whose output is the phase
+// difference between samples in radians.
 func NewDemodulator() *Demodulator {
-	return &Demodulator{}
+	return &Demodulator{scale: 1}
+}
+
+// NewFMDemodulator creates an FM demodulator for a signal sampled at
+// sampleRate whose output is normalized to its maximum deviation in Hz, so a
+// carrier deviated by maxDeviation gives ±1 whatever the sample rate.
+func NewFMDemodulator(sampleRate int, maxDeviation float64) *Demodulator {
+	return &Demodulator{scale: float32(float64(sampleRate) / (2 * math.Pi * maxDeviation))}
 }
 
 // Process demodulates a block of complex IQ samples into an audio signal.
@@ -28,7 +40,7 @@ func (d *Demodulator) Process(samples []complex64) []float32 {
 		// The angle of the resulting complex number is the phase difference.
 		prevConjugate := complex(real(prev), -imag(prev))
 		p := current * prevConjugate
-		output[i] = float32(cmplx.Phase(complex128(p)))
+		output[i] = float32(cmplx.Phase(complex128(p))) * d.scale
 		prev = current
 	}
 
@@ -117,3 +117,26 @@ func TestDemodulator_Statefulness(t *testing.T) {
 		}
 	}
 }
+
+func TestFMDemodulator_DeviationNormalized(t *testing.T) {
+	// The same deviation must give the same output at any sample rate.
+	tests := []struct {
+		sampleRate   int
+		maxDeviation float64
+	}{
+		{240000, 75000},
+		{48000, 2500},
+		{48000, 5000},
+	}
+	for _, tt := range tests {
+		demod := NewFMDemodulator(tt.sampleRate, tt.maxDeviation)
+		// A carrier held at half the maximum deviation.
+		samples := generateTestSignal(64, 2*math.Pi*0.5*tt.maxDeviation/float64(tt.sampleRate))
+		output := demod.Process(samples)
+		for i := 1; i < len(output); i++ {
+			if math.Abs(float64(output[i])-0.5) > 1e-4 {
+				t.Fatalf("%d Hz, %g Hz deviation: expected 0.5, but got %f at sample %d", tt.sampleRate, tt.maxDeviation, output[i], i)
+			}
+		}
+	}
+}
@@ -29,44 +29,105 @@ func DesignFIRLowPass(numTaps int, cutoff float64) []float64 {
 	return taps
 }
 
-// Resample changes the sample rate of a signal using a windowed-sinc function.
-func Resample(input []float32, ratio float64) []float32 {
-	const windowSize = 16 // Number of taps on each side of the sample.
+// StreamResampler changes the sample rate of a stream by an arbitrary, and
+// possibly time-varying, ratio using a windowed-sinc interpolator. The
+// fractional input position and the samples still needed by the window carry
+// across calls to Process, so there are no discontinuities at block
+// boundaries. Output sample i corresponds to input time i/ratio.
+type StreamResampler struct {
+	step      float64 // Input samples per output sample.
+	halfWidth int     // Number of taps on each side of the output position.
+	table     []float32
+	history   []float32
+	pos       float64 // Position of the next output within history.
+}
+
+// kernelResolution is the number of precomputed kernel points per input sample.
+const kernelResolution = 128
 
-	outputLen := int(float64(len(input)) * ratio)
-	if outputLen == 0 {
-		return nil
+// NewStreamResampler creates a resampler producing ratio output samples per
+// input sample. The interpolation kernel spans numTaps input samples and cuts
+// off at cutoff, normalized to the input sample rate. The cutoff is fixed at
+// creation, so later ratio changes should stay close to the initial ratio.
+func NewStreamResampler(ratio float64, numTaps int, cutoff float64) *StreamResampler {
+	halfWidth := max(numTaps/2, 1)
+	fc := 2 * min(cutoff, 0.5*min(1, ratio))
+
+	// Precompute one side of the symmetric, Hamming windowed sinc kernel.
+	table := make([]float32, halfWidth*kernelResolution+2)
+	for k := range table {
+		x := float64(k) / kernelResolution
+		if x >= float64(halfWidth) {
+			continue
+		}
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(math.Pi*fc*x) / (math.Pi * fc * x)
+		}
+		window := 0.54 + 0.46*math.Cos(math.Pi*x/float64(halfWidth))
+		table[k] = float32(sinc * window)
 	}
-	output := make([]float32, outputLen)
-	invRatio := 1.0 / ratio
 
-	for i := range output {
-		inPos := float64(i) * invRatio
-		centerIndex := int(math.Round(inPos))
+	return &StreamResampler{
+		step:      1 / ratio,
+		halfWidth: halfWidth,
+		table:     table,
+		// Start with a window of silence so the first output lines up with
+		// the first input sample.
+		history: make([]float32, halfWidth),
+		pos:     float64(halfWidth),
+	}
+}
 
-		var acc, sumTaps float32
-		for j := -windowSize; j < windowSize; j++ {
-			inputIndex := centerIndex + j
-			if inputIndex < 0 || inputIndex >= len(input) {
-				continue
-			}
+// SetRatio changes the number of output samples per input sample, taking
+// effect from the next output sample.
+func (r *StreamResampler) SetRatio(ratio float64) {
+	r.step = 1 / ratio
+}
+
+// Ratio returns the current number of output samples per input sample.
+func (r *StreamResampler) Ratio() float64 {
+	return 1 / r.step
+}
+
+// kernel returns the interpolation kernel at x input samples from the centre.
+func (r *StreamResampler) kernel(x float64) float32 {
+	idx := math.Abs(x) * kernelResolution
+	k := int(idx)
+	frac := float32(idx - float64(k))
+	return r.table[k] + frac*(r.table[k+1]-r.table[k])
+}
 
-			sincPos := inPos - float64(inputIndex)
-			piSincPos := math.Pi * sincPos
-			sinc := float32(1.0)
-			if piSincPos != 0 {
-				sinc = float32(math.Sin(piSincPos) / piSincPos)
-			}
+// Process resamples a block of input samples. Output is delayed until the
+// window has enough input on both sides of each output position.
+func (r *StreamResampler) Process(input []float32) []float32 {
+	buffer := make([]float32, len(r.history)+len(input))
+	copy(buffer, r.history)
+	copy(buffer[len(r.history):], input)
 
-			window := 0.54 - 0.46*math.Cos(2*math.Pi*float64(j+windowSize)/float64(2*windowSize))
-			tap := sinc * float32(window)
+	output := make([]float32, 0, int(float64(len(input))/r.step)+1)
+	for {
+		center := int(r.pos)
+		if center+r.halfWidth >= len(buffer) {
+			break
+		}
 
-			acc += input[inputIndex] * tap
+		var acc, sumTaps float32
+		for j := center - r.halfWidth + 1; j <= center+r.halfWidth; j++ {
+			tap := r.kernel(float64(j) - r.pos)
+			acc += buffer[j] * tap
 			sumTaps += tap
 		}
 		if sumTaps != 0 {
-			output[i] = acc / sumTaps
+			acc /= sumTaps
 		}
+		output = append(output, acc)
+		r.pos += r.step
 	}
+
+	// Keep only the samples the window still needs.
+	drop := min(max(int(r.pos)-r.halfWidth+1, 0), len(buffer))
+	r.history = append(r.history[:0], buffer[drop:]...)
+	r.pos -= float64(drop)
 	return output
 }
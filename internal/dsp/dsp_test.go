@@ -1,6 +1,7 @@
 package dsp
 
 import (
+	"math"
 	"testing"
 )
 
@@ -32,10 +33,10 @@ func TestDesignFIRLowPass(t *testing.T) {
 	}
 }
 
-// TestFIRFilter_DecimationAndState checks the decimating filter.
-func TestFIRFilter_DecimationAndState(t *testing.T) {
+// TestFIRFilter_State checks that the filter gives the same output whether the
+// input arrives in one block or several.
+func TestFIRFilter_State(t *testing.T) {
 	taps := []float64{0.1, 0.2, 0.4, 0.2, 0.1}
-	ratio := 0.5 // Decimate by 2
 
 	input := make([]float32, 100)
 	for i := range input {
@@ -44,16 +45,16 @@ func TestFIRFilter_DecimationAndState(t *testing.T) {
 
 	// Process in one go
 	fir1 := NewFIRFilter(taps)
-	fullOutput := fir1.Process(input, ratio)
+	fullOutput := fir1.Process(input)
 
-	// Process in chunks
+	// Process in uneven chunks
 	fir2 := NewFIRFilter(taps)
-	chunk1 := fir2.Process(input[:50], ratio)
-	chunk2 := fir2.Process(input[50:], ratio)
+	chunk1 := fir2.Process(input[:37])
+	chunk2 := fir2.Process(input[37:])
 	chunkedOutput := append(chunk1, chunk2...)
 
-	if len(fullOutput) != len(chunkedOutput) {
-		t.Fatalf("Mismatched lengths: full=%d, chunked=%d", len(fullOutput), len(chunkedOutput))
+	if len(fullOutput) != len(input) || len(chunkedOutput) != len(input) {
+		t.Fatalf("Expected %d samples out, but got full=%d, chunked=%d", len(input), len(fullOutput), len(chunkedOutput))
 	}
 
 	for i := range fullOutput {
@@ -61,6 +62,16 @@ func TestFIRFilter_DecimationAndState(t *testing.T) {
 			t.Errorf("Mismatch at index %d: full=%f, chunked=%f", i, fullOutput[i], chunkedOutput[i])
 		}
 	}
+
+	// An impulse comes out as the taps.
+	impulse := make([]float32, 8)
+	impulse[0] = 1
+	output := NewFIRFilter(taps).Process(impulse)
+	for i, tap := range taps {
+		if !almostEqual(output[i], float32(tap)) {
+			t.Errorf("Expected impulse response %f at %d, but got %f", tap, i, output[i])
+		}
+	}
 }
 
 // TestDeemphasis checks the de-emphasis filter's response to a step input.
@@ -99,3 +110,60 @@ func TestDeemphasis(t *testing.T) {
 		t.Errorf("Expected de-emphasis to settle near 1.0, but got %f", finalOutput)
 	}
 }
+
+// TestStreamResampler_Chunked checks that the resampler produces the same
+// output however the input is split into blocks.
+func TestStreamResampler_Chunked(t *testing.T) {
+	const ratio = 240_000.0 / 2_048_000
+	input := make([]float32, 20000)
+	for i := range input {
+		input[i] = float32(math.Sin(2 * math.Pi * 0.01 * float64(i)))
+	}
+
+	fullOutput := NewStreamResampler(ratio, 32, 0.5).Process(input)
+
+	chunked := NewStreamResampler(ratio, 32, 0.5)
+	var chunkedOutput []float32
+	for i, size := 0, 1; i < len(input); i, size = i+size, size%700+7 {
+		chunkedOutput = append(chunkedOutput, chunked.Process(input[i:min(i+size, len(input))])...)
+	}
+
+	if len(fullOutput) != len(chunkedOutput) {
+		t.Fatalf("Mismatched lengths: full=%d, chunked=%d", len(fullOutput), len(chunkedOutput))
+	}
+	for i := range fullOutput {
+		if !almostEqual(fullOutput[i], chunkedOutput[i]) {
+			t.Fatalf("Mismatch at index %d: full=%f, chunked=%f", i, fullOutput[i], chunkedOutput[i])
+		}
+	}
+}
+
+// TestStreamResampler_Tone checks that a tone is interpolated at the right
+// output times, including after a change of ratio.
+func TestStreamResampler_Tone(t *testing.T) {
+	const inputRate = 44100.0
+	const freq = 1000.0
+	input := make([]float32, 44100)
+	for i := range input {
+		input[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / inputRate))
+	}
+
+	r := NewStreamResampler(48000/inputRate, 32, 0.5)
+	first := r.Process(input[:len(input)/2])
+	inputTime := float64(len(first)) / (48000 / inputRate)
+	r.SetRatio(50000 / inputRate)
+	second := r.Process(input[len(input)/2:])
+
+	for i := 32; i < len(first); i++ {
+		want := math.Sin(2 * math.Pi * freq * float64(i) / 48000)
+		if math.Abs(float64(first[i])-want) > 1e-2 {
+			t.Fatalf("Sample %d: expected %f, got %f", i, want, first[i])
+		}
+	}
+	for i := 0; i < len(second)-32; i++ {
+		want := math.Sin(2 * math.Pi * freq * (inputTime/inputRate + float64(i)/50000))
+		if math.Abs(float64(second[i])-want) > 1e-2 {
+			t.Fatalf("Sample %d after ratio change: expected %f, got %f", i, want, second[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package dsp
+
+import "math"
+
+// Limiter is a look-ahead peak limiter that keeps audio within a ceiling
+// without clipping. The audio is delayed by the look-ahead time, so the gain
+// can start falling before a peak arrives: the gain each peak needs is held
+// for the look-ahead window and then averaged over it, which ramps the gain
+// down smoothly ahead of the peak and guarantees the peak itself is brought
+// within the ceiling. The gain then recovers at the release rate.
+type Limiter struct {
+	ceiling float64 // Linear.
+	release float64 // Gain coefficient while recovering.
+	gain    float64
+
+	// Look-ahead state: the gain each recent input sample needs, the minimum
+	// over each window of those, whose running sum gives the average, and
+	// the delayed audio of each channel.
+	required []float64
+	minimum  []float64
+	sum      float64
+	pos      int
+	delay    [][]float32
+	delayPos int
+
+	lowestGain float64
+	limited    int64
+	samples    int64
+}
+
+// LimiterStats reports how much a Limiter has reduced the gain.
+type LimiterStats struct {
+	GainReduction     float64 // Current gain reduction in dB.
+	PeakGainReduction float64 // Largest gain reduction so far in dB.
+	Limited           int64   // Samples output with the gain reduced by more than 0.1 dB.
+	Samples           int64   // Samples output in total.
+}
+
+// limitedGain is the gain below which a sample counts as limited, 0.1 dB of
+// reduction, so the end of the release isn't counted.
+const limitedGain = 0.9886
+
+// NewLimiter creates a limiter for the given number of channels of audio
+// sampled at sampleRate. ceiling is the highest level in dBFS the output
+// reaches, lookahead the delay in seconds over which the gain ramps down
+// before a peak, and release the time constant in seconds of its recovery.
+func NewLimiter(sampleRate, channels int, ceiling, lookahead, release float64) *Limiter {
+	fs := float64(sampleRate)
+	window := int(lookahead*fs) + 1
+	l := &Limiter{
+		ceiling:    math.Pow(10, ceiling/20),
+		release:    1 - math.Exp(-1/(release*fs)),
+		gain:       1,
+		lowestGain: 1,
+		required:   make([]float64, window),
+		minimum:    make([]float64, window),
+		sum:        float64(window),
+		delay:      make([][]float32, channels),
+	}
+	for i := range window {
+		l.required[i] = 1
+		l.minimum[i] = 1
+	}
+	for c := range l.delay {
+		l.delay[c] = make([]float32, window-1)
+	}
+	return l
+}
+
+// Process limits the given channels in place, delaying them by the look-ahead
+// time. There must be as many channels as the limiter was created with, all
+// the same length, and they share one gain so a stereo image doesn't shift.
+func (l *Limiter) Process(channels ...[]float32) {
+	window := len(l.required)
+	for i := range channels[0] {
+		var peak float64
+		for _, channel := range channels {
+			peak = max(peak, math.Abs(float64(channel[i])))
+		}
+		need := 1.0
+		if peak > l.ceiling {
+			need = l.ceiling / peak
+		}
+
+		// Hold the lowest gain needed by any sample in the window, then
+		// average the held gains over the window.
+		l.required[l.pos] = need
+		lowest := 1.0
+		for _, r := range l.required {
+			lowest = min(lowest, r)
+		}
+		l.sum += lowest - l.minimum[l.pos]
+		l.minimum[l.pos] = lowest
+		target := min(l.sum/float64(window), 1)
+
+		if target < l.gain {
+			l.gain = target
+		} else {
+			l.gain += l.release * (target - l.gain)
+		}
+
+		for c, channel := range channels {
+			x := channel[i]
+			if d := l.delay[c]; len(d) > 0 {
+				x, d[l.delayPos] = d[l.delayPos], x
+			}
+			// The average can exceed the gain needed by a rounding error, so
+			// make sure of the ceiling.
+			y := max(min(float64(x)*l.gain, l.ceiling), -l.ceiling)
+			channel[i] = float32(y)
+		}
+		l.pos = (l.pos + 1) % window
+		if window > 1 {
+			l.delayPos = (l.delayPos + 1) % (window - 1)
+		}
+
+		l.samples++
+		if l.gain < limitedGain {
+			l.limited++
+		}
+		l.lowestGain = min(l.lowestGain, l.gain)
+	}
+}
+
+// Stats returns the gain reduction statistics.
+func (l *Limiter) Stats() LimiterStats {
+	return LimiterStats{
+		GainReduction:     -20 * math.Log10(l.gain),
+		PeakGainReduction: -20 * math.Log10(l.lowestGain),
+		Limited:           l.limited,
+		Samples:           l.samples,
+	}
+}
@@ -0,0 +1,49 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLimiter_Ceiling(t *testing.T) {
+	const sampleRate = 48000
+	const ceiling = -1.0
+	const lookahead = 0.005
+	limit := math.Pow(10, ceiling/20)
+	delay := int(lookahead * sampleRate)
+
+	// A quiet tone with a burst 12 dB over full scale in the middle.
+	input := sineBlock(sampleRate, sampleRate, 1000, 0.5)
+	for i := sampleRate / 2; i < sampleRate/2+sampleRate/10; i++ {
+		input[i] *= 8
+	}
+
+	limiter := NewLimiter(sampleRate, 1, ceiling, lookahead, 0.05)
+	var output []float32
+	for start := 0; start < len(input); start += 1000 {
+		block := append([]float32(nil), input[start:min(start+1000, len(input))]...)
+		limiter.Process(block)
+		output = append(output, block...)
+	}
+
+	if got := peak(output); got > limit+1e-6 {
+		t.Errorf("Expected no sample above %f, but got %f", limit, got)
+	}
+	// Away from the burst the audio passes unchanged, only delayed.
+	for i := delay; i < sampleRate/2-delay; i++ {
+		if output[i] != input[i-delay] {
+			t.Fatalf("Expected sample %d delayed unchanged as %f, but got %f", i-delay, input[i-delay], output[i])
+		}
+	}
+
+	stats := limiter.Stats()
+	if want := 20*math.Log10(4) - ceiling; math.Abs(stats.PeakGainReduction-want) > 0.5 {
+		t.Errorf("Expected a peak gain reduction of %.1f dB, but got %.1f dB", want, stats.PeakGainReduction)
+	}
+	// The burst is limited, and then the gain takes about five release time
+	// constants to recover within 0.1 dB.
+	const burst, recovery = sampleRate / 10, sampleRate / 4
+	if stats.Samples != sampleRate || stats.Limited < burst || stats.Limited > burst+recovery {
+		t.Errorf("Expected %d to %d of %d samples limited, but got %d of %d", burst, burst+recovery, sampleRate, stats.Limited, stats.Samples)
+	}
+}
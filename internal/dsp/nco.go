@@ -0,0 +1,50 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// NCO is a numerically controlled oscillator that translates a complex signal
+// in frequency, for example to move an off-center station down to DC.
+type NCO struct {
+	phasor   complex128
+	rotation complex128
+	freq     float64
+}
+
+// NewNCO creates an oscillator that shifts a signal sampled at sampleRate by
+// freq Hz. A negative freq moves the signal down in frequency.
+func NewNCO(sampleRate int, freq float64) *NCO {
+	n := &NCO{phasor: 1}
+	n.SetFrequency(sampleRate, freq)
+	return n
+}
+
+// SetFrequency changes the frequency shift while keeping the oscillator's
+// phase continuous.
+func (n *NCO) SetFrequency(sampleRate int, freq float64) {
+	n.freq = freq
+	n.rotation = cmplx.Rect(1, 2*math.Pi*freq/float64(sampleRate))
+}
+
+// Frequency returns the current frequency shift in Hz.
+func (n *NCO) Frequency() float64 {
+	return n.freq
+}
+
+// Process shifts a block of samples in place and returns it.
+func (n *NCO) Process(samples []complex64) []complex64 {
+	if n.freq == 0 {
+		return samples
+	}
+	phasor := n.phasor
+	for i, s := range samples {
+		samples[i] = s * complex64(phasor)
+		phasor *= n.rotation
+	}
+	// Renormalize once per block so rounding errors do not change the
+	// oscillator's amplitude over time.
+	n.phasor = phasor / complex(cmplx.Abs(phasor), 0)
+	return samples
+}
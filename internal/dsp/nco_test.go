@@ -0,0 +1,48 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// generateTone creates a complex tone at freq Hz.
+func generateTone(sampleRate, numSamples int, freq float64) []complex64 {
+	samples := make([]complex64, numSamples)
+	for i := range samples {
+		phase := 2 * math.Pi * freq * float64(i) / float64(sampleRate)
+		samples[i] = complex(float32(math.Cos(phase)), float32(math.Sin(phase)))
+	}
+	return samples
+}
+
+func TestNCO_ShiftToDC(t *testing.T) {
+	const sampleRate = 2_000_000
+	const offset = 300_000.0
+	const chunkSize = 1000
+
+	samples := generateTone(sampleRate, 10*chunkSize, offset)
+	nco := NewNCO(sampleRate, -offset)
+
+	// Process in chunks to check the phase carries across blocks.
+	for i := 0; i < len(samples); i += chunkSize {
+		nco.Process(samples[i : i+chunkSize])
+	}
+
+	for i, s := range samples {
+		if math.Abs(float64(real(s))-1) > 1e-3 || math.Abs(float64(imag(s))) > 1e-3 {
+			t.Fatalf("Sample %d: expected tone shifted to DC (1+0i), got %v", i, s)
+		}
+	}
+}
+
+func TestNCO_ZeroFrequency(t *testing.T) {
+	samples := generateTone(48000, 64, 1000)
+	want := append([]complex64(nil), samples...)
+
+	NewNCO(48000, 0).Process(samples)
+	for i := range samples {
+		if samples[i] != want[i] {
+			t.Fatalf("Sample %d: expected unchanged sample %v, got %v", i, want[i], samples[i])
+		}
+	}
+}
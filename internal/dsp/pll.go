@@ -0,0 +1,80 @@
+package dsp
+
+import "math"
+
+// PLL implements a second-order phase-locked loop that tracks a real-valued
+// sinusoid embedded in a wider signal, such as the 19 kHz FM stereo pilot.
+// The loop locks its oscillator to sin(phase) of the tracked tone.
+type PLL struct {
+	phase   float64 // Oscillator phase in radians.
+	nominal float64 // Nominal frequency in radians per sample.
+	freq    float64 // Frequency correction in radians per sample.
+	maxFreq float64 // Limit for the frequency correction.
+	alpha   float64 // Proportional loop gain.
+	beta    float64 // Integral loop gain.
+
+	// Smoothed lock detector state.
+	smooth     float64
+	inPhase    float64
+	quadrature float64
+	power      float64
+}
+
+// NewPLL creates a PLL tracking a tone at freq Hz in a signal sampled at
+// sampleRate. loopBandwidth sets the natural frequency of the loop in Hz and
+// maxOffset limits how far from freq the loop may pull, also in Hz.
+func NewPLL(sampleRate int, freq, loopBandwidth, maxOffset float64) *PLL {
+	fs := float64(sampleRate)
+	wn := 2 * math.Pi * loopBandwidth / fs
+	const damping = 0.707
+	return &PLL{
+		nominal: 2 * math.Pi * freq / fs,
+		maxFreq: 2 * math.Pi * maxOffset / fs,
+		alpha:   2 * damping * wn,
+		beta:    wn * wn,
+		// Lock detector time constant of roughly 20ms.
+		smooth: 1 - math.Exp(-1/(0.02*fs)),
+	}
+}
+
+// Update advances the loop by one input sample and returns the oscillator
+// phase that was aligned with that sample.
+func (p *PLL) Update(x float64) float64 {
+	phase := p.phase
+	s, c := math.Sincos(phase)
+
+	// Lock detector: when locked to A*sin(phase) the in-phase average settles
+	// at A/2 and the quadrature average at zero.
+	p.inPhase += p.smooth * (x*s - p.inPhase)
+	p.quadrature += p.smooth * (x*c - p.quadrature)
+	p.power += p.smooth * (x*x - p.power)
+
+	// Phase detector, normalized by the tracked amplitude so the loop
+	// bandwidth does not depend on the input level.
+	norm := math.Max(math.Abs(p.inPhase), 0.01*math.Sqrt(p.power)+1e-12)
+	e := x * c / norm
+
+	p.freq += p.beta * e
+	if p.freq > p.maxFreq {
+		p.freq = p.maxFreq
+	} else if p.freq < -p.maxFreq {
+		p.freq = -p.maxFreq
+	}
+
+	p.phase = math.Mod(phase+p.nominal+p.freq+p.alpha*e, 2*math.Pi)
+	return phase
+}
+
+// Lock returns the fraction of the input power that is coherent with the
+// oscillator, between 0 (no tone or unlocked) and 1 (pure locked tone).
+func (p *PLL) Lock() float64 {
+	if p.power <= 0 || p.inPhase <= 0 {
+		return 0
+	}
+	return math.Min(2*p.inPhase*p.inPhase/p.power, 1)
+}
+
+// Frequency returns the current frequency of the oscillator in Hz.
+func (p *PLL) Frequency(sampleRate int) float64 {
+	return (p.nominal + p.freq) * float64(sampleRate) / (2 * math.Pi)
+}
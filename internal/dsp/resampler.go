@@ -0,0 +1,168 @@
+package dsp
+
+import "math"
+
+// Resampler converts a stream from one sample rate to another.
+type Resampler interface {
+	Process(input []float32) []float32
+}
+
+// maxRationalFactor is the largest interpolation factor NewResampler will use
+// for a RationalResampler, which keeps a prototype filter of up*numTaps taps.
+const maxRationalFactor = 64
+
+// NewResampler creates a resampler from inputRate to outputRate with numTaps
+// taps per output sample and the given cutoff, normalized to inputRate. Rates
+// with a small rational relationship, such as 2.048 MHz to 240 kHz (15/128),
+// are resampled exactly by a RationalResampler. Anything else falls back to a
+// StreamResampler.
+func NewResampler(inputRate, outputRate, numTaps int, cutoff float64) Resampler {
+	up, down := RationalRatio(inputRate, outputRate)
+	if up <= maxRationalFactor {
+		return NewRationalResampler(up, down, numTaps, cutoff)
+	}
+	return NewStreamResampler(float64(outputRate)/float64(inputRate), numTaps, cutoff)
+}
+
+// hammingTransitionWidth is the width of the transition band of a Hamming
+// windowed filter, in multiples of the sample rate divided by its taps.
+const hammingTransitionWidth = 3.3
+
+// MultistageResampler changes the sample rate of a stream through a chain of
+// resamplers, each feeding the next.
+type MultistageResampler struct {
+	stages []Resampler
+}
+
+// NewMultistageResampler creates a resampler like NewResampler, but one that
+// splits a large decimation into stages when a single filter of numTaps taps
+// at inputRate would have a transition band wider than half the cutoff. Each
+// early stage decimates to the lowest multiple of outputRate whose alias of
+// the output band falls beyond its transition band, so only the last stage,
+// at a low rate where its transition is narrow, sets the final cutoff. With
+// 251 taps, a 4 kHz cutoff from 2 MHz to 48 kHz decimates to 96 kHz first,
+// narrowing the transition band from 26 kHz to 1.3 kHz.
+func NewMultistageResampler(inputRate, outputRate, numTaps int, cutoff float64) Resampler {
+	var stages []Resampler
+	for {
+		width := hammingTransitionWidth * float64(inputRate) / float64(numTaps)
+		stageRate := max(2, int(math.Ceil(1+width/float64(outputRate)))) * outputRate
+		if width <= cutoff*float64(inputRate)/2 || stageRate >= inputRate {
+			break
+		}
+		// Cut off halfway between the output band and its first alias.
+		stages = append(stages, NewResampler(inputRate, stageRate, numTaps, 0.5*float64(stageRate)/float64(inputRate)))
+		cutoff *= float64(inputRate) / float64(stageRate)
+		inputRate = stageRate
+	}
+
+	last := NewResampler(inputRate, outputRate, numTaps, cutoff)
+	if len(stages) == 0 {
+		return last
+	}
+	return &MultistageResampler{stages: append(stages, last)}
+}
+
+// Stages returns the number of resamplers in the chain.
+func (m *MultistageResampler) Stages() int {
+	return len(m.stages)
+}
+
+// Process resamples a block of input samples through every stage.
+func (m *MultistageResampler) Process(input []float32) []float32 {
+	for _, stage := range m.stages {
+		input = stage.Process(input)
+	}
+	return input
+}
+
+// RationalResampler changes the sample rate of a stream by an exact factor of
+// up/down. It is a polyphase implementation of upsampling by up, low-pass
+// filtering and downsampling by down, which only evaluates the filter phase
+// needed for each output sample. The output timing and filter history carry
+// across calls to Process, so a stream can be resampled in blocks of any size.
+type RationalResampler struct {
+	up     int
+	down   int
+	phases [][]float32 // Polyphase sub-filters, stored in reverse for convolution.
+	// Index of the next output in the upsampled domain, relative to the first
+	// sample of the next input block.
+	pos     int
+	history []float32
+}
+
+// RationalRatio reduces outputRate/inputRate to the up/down factors of a
+// RationalResampler.
+func RationalRatio(inputRate, outputRate int) (up, down int) {
+	g := gcd(inputRate, outputRate)
+	return outputRate / g, inputRate / g
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// NewRationalResampler creates a resampler by a factor of up/down. The
+// anti-aliasing filter has tapsPerPhase taps per output sample and cuts off at
+// cutoff, normalized to the input sample rate. A cutoff above the output
+// Nyquist frequency is lowered to it, so the output never aliases.
+func NewRationalResampler(up, down, tapsPerPhase int, cutoff float64) *RationalResampler {
+	g := gcd(up, down)
+	up, down = up/g, down/g
+
+	nyquist := 0.5 * min(1, float64(up)/float64(down))
+	cutoff = min(cutoff, nyquist)
+
+	// Design the prototype filter at the upsampled rate, where the cutoff is
+	// up times smaller, and scale it to make up for the inserted zeros.
+	prototype := DesignFIRLowPass(tapsPerPhase*up, cutoff/float64(up))
+	phases := make([][]float32, up)
+	for p := range phases {
+		phases[p] = make([]float32, tapsPerPhase)
+		for j := range tapsPerPhase {
+			phases[p][tapsPerPhase-1-j] = float32(prototype[p+j*up] * float64(up))
+		}
+	}
+
+	return &RationalResampler{
+		up:      up,
+		down:    down,
+		phases:  phases,
+		history: make([]float32, tapsPerPhase-1),
+	}
+}
+
+// Ratio returns the reduced up and down factors of the resampler.
+func (r *RationalResampler) Ratio() (up, down int) {
+	return r.up, r.down
+}
+
+// Process resamples a block of input samples. It may return an empty slice
+// when the block is too short to produce an output sample.
+func (r *RationalResampler) Process(input []float32) []float32 {
+	taps := len(r.history) + 1
+	buffer := make([]float32, len(r.history)+len(input))
+	copy(buffer, r.history)
+	copy(buffer[len(r.history):], input)
+
+	end := len(input) * r.up
+	output := make([]float32, 0, (end-r.pos+r.down-1)/r.down)
+	pos := r.pos
+	for ; pos < end; pos += r.down {
+		n, p := pos/r.up, pos%r.up
+		window := buffer[n : n+taps]
+
+		var acc float32
+		for j, c := range r.phases[p] {
+			acc += window[j] * c
+		}
+		output = append(output, acc)
+	}
+
+	r.pos = pos - end
+	copy(r.history, buffer[len(buffer)-len(r.history):])
+	return output
+}
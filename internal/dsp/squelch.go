@@ -0,0 +1,181 @@
+package dsp
+
+import (
+	"fmt"
+	"math"
+)
+
+// SquelchDetector selects what a Squelch measures to decide whether a signal
+// is present.
+type SquelchDetector int
+
+const (
+	// PowerSquelch measures the power of the channel in dBFS. It suits AM,
+	// where the carrier power shows whether anyone is transmitting.
+	PowerSquelch SquelchDetector = iota
+	// NoiseSquelch measures the noise above the voice band on the FM
+	// discriminator output, which a carrier quietens, and reports it
+	// negated in dB, so a higher level means a cleaner signal. It suits NFM,
+	// where it opens on signal quality rather than strength.
+	NoiseSquelch
+)
+
+// Squelch tuning.
+const (
+	squelchTau        = 0.01 // Seconds over which the level is averaged.
+	squelchNoiseTaps  = 31   // Taps of the noise detector's high-pass filter.
+	squelchNoiseStart = 0.2  // Start of the noise band, normalized to the sample rate.
+)
+
+// SquelchEvent reports the squelch opening or closing.
+type SquelchEvent struct {
+	Open   bool
+	Sample int64   // Index of the input sample at which it happened.
+	Level  float64 // Detector level in dB at that moment.
+}
+
+func (e SquelchEvent) String() string {
+	state := "closed"
+	if e.Open {
+		state = "open"
+	}
+	return fmt.Sprintf("%s at sample %d (%.1f dB)", state, e.Sample, e.Level)
+}
+
+// Squelch mutes demodulated audio while no signal is present. It opens when
+// the detector level reaches the open threshold and closes once it has been
+// below the lower close threshold for the hang time, so a level hovering
+// around one threshold doesn't chatter. Tail elimination delays the audio, so
+// the last moments before the squelch closes, which carry the noise burst at
+// the end of a transmission, are muted too.
+type Squelch struct {
+	detector  SquelchDetector
+	openAt    float64
+	closeAt   float64
+	hang      int
+	smooth    float64
+	highPass  []float32 // Noise detector filter, stored in reverse for convolution.
+	noiseHist []float32
+
+	level    float64 // Averaged detector power, linear.
+	open     bool
+	hangLeft int
+	sample   int64
+
+	// Tail elimination delay lines for the audio and the squelch state.
+	delayAudio []float32
+	delayOpen  []bool
+	delayPos   int
+}
+
+// NewSquelch creates a squelch for a channel sampled at sampleRate. The
+// thresholds are detector levels in dB, with openThreshold above
+// closeThreshold. hang is how long in seconds the squelch stays open after
+// the level drops, and tail how many seconds of audio before it closes are
+// muted.
+func NewSquelch(sampleRate int, detector SquelchDetector, openThreshold, closeThreshold, hang, tail float64) *Squelch {
+	fs := float64(sampleRate)
+	s := &Squelch{
+		detector:   detector,
+		openAt:     openThreshold,
+		closeAt:    closeThreshold,
+		hang:       int(hang * fs),
+		smooth:     1 - math.Exp(-1/(squelchTau*fs)),
+		delayAudio: make([]float32, int(tail*fs)),
+		delayOpen:  make([]bool, int(tail*fs)),
+	}
+	if detector == NoiseSquelch {
+		// A high-pass filter made by subtracting a low-pass one from an
+		// impulse, passing only the noise above the voice band.
+		lowPass := DesignFIRLowPass(squelchNoiseTaps, squelchNoiseStart)
+		s.highPass = make([]float32, squelchNoiseTaps)
+		for i, tap := range lowPass {
+			s.highPass[squelchNoiseTaps-1-i] = float32(-tap)
+		}
+		s.highPass[squelchNoiseTaps/2] += 1
+		s.noiseHist = make([]float32, squelchNoiseTaps-1)
+	}
+	return s
+}
+
+// Open reports whether the squelch is open.
+func (s *Squelch) Open() bool {
+	return s.open
+}
+
+// Level returns the current detector level in dB.
+func (s *Squelch) Level() float64 {
+	level := 10 * math.Log10(s.level+1e-20)
+	if s.detector == NoiseSquelch {
+		return -level
+	}
+	return level
+}
+
+// Process gates a block of demodulated audio, measuring the signal from the
+// channel's baseband or from the audio itself according to the detector. The
+// two blocks must be the same length. It returns the gated audio, delayed by
+// the tail time, and any events that occurred during the block.
+func (s *Squelch) Process(baseband []complex64, audio []float32) ([]float32, []SquelchEvent) {
+	var power []float32
+	if s.detector == NoiseSquelch {
+		power = s.noisePower(audio)
+	}
+
+	var events []SquelchEvent
+	output := make([]float32, len(audio))
+	for i, x := range audio {
+		var p float64
+		if s.detector == NoiseSquelch {
+			p = float64(power[i])
+		} else {
+			p = float64(real(baseband[i]))*float64(real(baseband[i])) + float64(imag(baseband[i]))*float64(imag(baseband[i]))
+		}
+		s.level += s.smooth * (p - s.level)
+
+		level := s.Level()
+		switch {
+		case !s.open && level >= s.openAt:
+			s.open = true
+			s.hangLeft = s.hang
+			events = append(events, SquelchEvent{Open: true, Sample: s.sample, Level: level})
+		case s.open && level >= s.closeAt:
+			s.hangLeft = s.hang
+		case s.open && s.hangLeft > 0:
+			s.hangLeft--
+		case s.open:
+			s.open = false
+			events = append(events, SquelchEvent{Open: false, Sample: s.sample, Level: level})
+		}
+		s.sample++
+
+		// Only audio from while the squelch was open, and which is still
+		// open tail seconds later, is heard.
+		delayed, delayedOpen := x, s.open
+		if len(s.delayAudio) > 0 {
+			delayed, delayedOpen = s.delayAudio[s.delayPos], s.delayOpen[s.delayPos]
+			s.delayAudio[s.delayPos], s.delayOpen[s.delayPos] = x, s.open
+			s.delayPos = (s.delayPos + 1) % len(s.delayAudio)
+		}
+		if s.open && delayedOpen {
+			output[i] = delayed
+		}
+	}
+	return output, events
+}
+
+// noisePower returns the instantaneous power of the audio above the voice
+// band.
+func (s *Squelch) noisePower(audio []float32) []float32 {
+	buffer := append(s.noiseHist, audio...)
+	power := make([]float32, len(audio))
+	for i := range power {
+		var acc float32
+		for j, tap := range s.highPass {
+			acc += buffer[i+j] * tap
+		}
+		power[i] = acc * acc
+	}
+	s.noiseHist = append(s.noiseHist[:0], buffer[len(buffer)-len(s.noiseHist):]...)
+	return power
+}
@@ -0,0 +1,139 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// generateNFM creates one second of an FM carrier of the given amplitude,
+// deviated 2.5 kHz by a 1 kHz tone, in complex noise of unit power.
+func generateNFM(sampleRate int, amplitude float64, rng *rand.Rand) []complex64 {
+	samples := make([]complex64, sampleRate)
+	phase := 0.0
+	for i := range samples {
+		phase += 2 * math.Pi * 2500 * math.Sin(2*math.Pi*1000*float64(i)/float64(sampleRate)) / float64(sampleRate)
+		noise := complex(rng.NormFloat64(), rng.NormFloat64()) / math.Sqrt2
+		samples[i] = complex64(cmplx.Rect(amplitude, phase) + noise)
+	}
+	return samples
+}
+
+// runSquelch passes baseband and audio through s in blocks, collecting the
+// gated audio and the events.
+func runSquelch(s *Squelch, baseband []complex64, audio []float32) ([]float32, []SquelchEvent) {
+	const blockSize = 1000
+	var output []float32
+	var events []SquelchEvent
+	for start := 0; start < len(audio); start += blockSize {
+		end := min(start+blockSize, len(audio))
+		out, ev := s.Process(baseband[start:end], audio[start:end])
+		output = append(output, out...)
+		events = append(events, ev...)
+	}
+	return output, events
+}
+
+func TestSquelch_PowerHysteresis(t *testing.T) {
+	const sampleRate = 48000
+	const hang = 0.1
+
+	// A carrier at -20 dBFS from 0.5 s to 1.5 s, with a brief fade to
+	// -42 dBFS at 1 s, between the thresholds, which must not close it.
+	baseband := make([]complex64, 2*sampleRate)
+	audio := make([]float32, len(baseband))
+	for i := range baseband {
+		amplitude := 0.001
+		if i >= sampleRate/2 && i < 3*sampleRate/2 {
+			amplitude = 0.1
+			if i >= sampleRate && i < sampleRate+sampleRate/20 {
+				amplitude = 0.008
+			}
+		}
+		baseband[i] = complex(float32(amplitude), 0)
+		audio[i] = 1
+	}
+
+	s := NewSquelch(sampleRate, PowerSquelch, -40, -45, hang, 0)
+	output, events := runSquelch(s, baseband, audio)
+
+	if len(events) != 2 || !events[0].Open || events[1].Open {
+		t.Fatalf("Expected one open and one close event, but got %v", events)
+	}
+	if at := float64(events[0].Sample) / sampleRate; math.Abs(at-0.5) > 0.02 {
+		t.Errorf("Expected the squelch to open at 0.5 s, but it opened at %.3f s", at)
+	}
+	// The averaged level takes about 60 ms to fall 25 dB to the close
+	// threshold, then the hang time runs.
+	if at := float64(events[1].Sample) / sampleRate; math.Abs(at-(1.56+hang)) > 0.02 {
+		t.Errorf("Expected the squelch to close at %.3f s, but it closed at %.3f s", 1.56+hang, at)
+	}
+	for i, x := range output {
+		if want := i >= int(events[0].Sample) && i < int(events[1].Sample); (x != 0) != want {
+			t.Fatalf("Expected audio %v at sample %d, but got %f", want, i, x)
+		}
+	}
+}
+
+func TestSquelch_NoiseDetector(t *testing.T) {
+	const sampleRate = 48000
+	rng := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name      string
+		amplitude float64
+		wantOpen  bool
+	}{
+		{"noise only", 0, false},
+		{"weak signal", 0.5, false},
+		{"strong signal", 10, true},
+	}
+	for _, tt := range tests {
+		baseband := generateNFM(sampleRate, tt.amplitude, rng)
+		audio := NewFMDemodulator(sampleRate, 2500).Process(baseband)
+
+		s := NewSquelch(sampleRate, NoiseSquelch, 0, -5, 0, 0)
+		runSquelch(s, baseband, audio)
+		if s.Open() != tt.wantOpen {
+			t.Errorf("%s: expected open=%v, but got %v at %.1f dB", tt.name, tt.wantOpen, s.Open(), s.Level())
+		}
+	}
+}
+
+func TestSquelch_TailElimination(t *testing.T) {
+	const sampleRate = 48000
+	const tail = 0.05
+
+	// A carrier for the first half second, then nothing.
+	baseband := make([]complex64, sampleRate)
+	audio := make([]float32, len(baseband))
+	for i := range baseband {
+		if i < sampleRate/2 {
+			baseband[i] = 1
+		}
+		audio[i] = float32(i)
+	}
+
+	s := NewSquelch(sampleRate, PowerSquelch, -20, -30, 0, tail)
+	output, events := runSquelch(s, baseband, audio)
+	if len(events) != 2 {
+		t.Fatalf("Expected one open and one close event, but got %v", events)
+	}
+
+	// The audio is delayed by the tail, and the tail before the close is
+	// never heard.
+	delay := int(tail * sampleRate)
+	var last float32
+	for i, x := range output {
+		if x != 0 {
+			if want := float32(i - delay); x != want {
+				t.Fatalf("Expected delayed sample %f at %d, but got %f", want, i, x)
+			}
+			last = x
+		}
+	}
+	if want := float32(events[1].Sample - int64(delay) - 1); last != want {
+		t.Errorf("Expected the last audio heard to be sample %f, but got %f", want, last)
+	}
+}
@@ -0,0 +1,77 @@
+package dsp
+
+// Sideband selects which side of the suppressed carrier an SSBDemodulator
+// receives.
+type Sideband int
+
+const (
+	USB Sideband = iota // Upper sideband, above the carrier.
+	LSB                 // Lower sideband, below the carrier.
+)
+
+// SSBDemodulator recovers single-sideband or CW audio from complex baseband
+// with the suppressed carrier at DC, using the Weaver method: the wanted
+// sideband is shifted so that the middle of its passband sits at DC, low-pass
+// filtered to the passband, and shifted up to its audio frequency, where the
+// real part is the audio. Selecting the sideband with complex shifts means
+// the opposite sideband is rejected by the low-pass filter, without the long
+// Hilbert transformer of the phasing method.
+type SSBDemodulator struct {
+	down    *NCO // Moves the middle of the passband to DC.
+	up      *NCO // Moves the filtered passband to its audio frequency.
+	filterI *FIRFilter
+	filterQ *FIRFilter
+}
+
+// NewSSBDemodulator creates a demodulator for the given sideband of baseband
+// sampled at sampleRate. low and high are the edges of the audio passband in
+// Hz, such as 300 and 2700 for voice. The BFO offset, also in Hz, raises the
+// pitch of the audio: a signal f Hz from the carrier is heard at f+bfo Hz.
+// The passband filter has numTaps taps.
+func NewSSBDemodulator(sampleRate int, sideband Sideband, low, high, bfo float64, numTaps int) *SSBDemodulator {
+	sign := 1.0
+	if sideband == LSB {
+		sign = -1
+	}
+	middle := (low + high) / 2
+	taps := DesignFIRLowPass(numTaps, (high-low)/2/float64(sampleRate))
+	return &SSBDemodulator{
+		down:    NewNCO(sampleRate, -sign*(middle-bfo)),
+		up:      NewNCO(sampleRate, sign*middle),
+		filterI: NewFIRFilter(taps),
+		filterQ: NewFIRFilter(taps),
+	}
+}
+
+// NewCWDemodulator creates a demodulator for Morse code keyed carriers at DC,
+// heard as a tone of pitch Hz. Only signals within bandwidth/2 Hz of DC pass
+// the filter.
+func NewCWDemodulator(sampleRate int, pitch, bandwidth float64, numTaps int) *SSBDemodulator {
+	return NewSSBDemodulator(sampleRate, USB, pitch-bandwidth/2, pitch+bandwidth/2, pitch, numTaps)
+}
+
+// Process demodulates a block of complex baseband samples into audio.
+func (d *SSBDemodulator) Process(samples []complex64) []float32 {
+	shifted := d.down.Process(append([]complex64(nil), samples...))
+
+	I := make([]float32, len(shifted))
+	Q := make([]float32, len(shifted))
+	for i, s := range shifted {
+		I[i] = real(s)
+		Q[i] = imag(s)
+	}
+	I = d.filterI.Process(I)
+	Q = d.filterQ.Process(Q)
+
+	filtered := make([]complex64, min(len(I), len(Q)))
+	for i := range filtered {
+		filtered[i] = complex(I[i], Q[i])
+	}
+	d.up.Process(filtered)
+
+	output := make([]float32, len(filtered))
+	for i, s := range filtered {
+		output[i] = real(s)
+	}
+	return output
+}
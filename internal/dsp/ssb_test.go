@@ -0,0 +1,71 @@
+package dsp
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// toneLevel returns the amplitude of the freq Hz component of samples.
+func toneLevel(samples []float32, sampleRate int, freq float64) float64 {
+	var sum complex128
+	for i, s := range samples {
+		sum += complex(float64(s), 0) * cmplx.Rect(1, -2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+	return 2 * cmplx.Abs(sum) / float64(len(samples))
+}
+
+// demodulate runs baseband tones at the given offsets from the carrier
+// through d in blocks, returning the audio after the filter has settled.
+func demodulate(d *SSBDemodulator, sampleRate int, offsets ...float64) []float32 {
+	signal := make([]complex64, sampleRate/2)
+	for _, offset := range offsets {
+		for i := range signal {
+			signal[i] += complex64(cmplx.Rect(0.5, 2*math.Pi*offset*float64(i)/float64(sampleRate)))
+		}
+	}
+	var audio []float32
+	for start := 0; start < len(signal); start += 1000 {
+		audio = append(audio, d.Process(signal[start:min(start+1000, len(signal))])...)
+	}
+	return audio[len(audio)/2:]
+}
+
+func TestSSBDemodulator(t *testing.T) {
+	const sampleRate = 12000
+	tests := []struct {
+		name       string
+		demod      *SSBDemodulator
+		wantFreq   float64 // Audio frequency of the wanted signal.
+		rejectFreq float64 // Audio frequency the unwanted signal would have.
+	}{
+		// A tone 1 kHz above the carrier and another 1.5 kHz below it.
+		{"USB", NewSSBDemodulator(sampleRate, USB, 300, 2700, 0, 255), 1000, 1500},
+		{"LSB", NewSSBDemodulator(sampleRate, LSB, 300, 2700, 0, 255), 1500, 1000},
+		{"USB with BFO", NewSSBDemodulator(sampleRate, USB, 300, 2700, 200, 255), 1200, 1300},
+	}
+	for _, tt := range tests {
+		audio := demodulate(tt.demod, sampleRate, 1000, -1500)
+		wanted, rejected := toneLevel(audio, sampleRate, tt.wantFreq), toneLevel(audio, sampleRate, tt.rejectFreq)
+		if math.Abs(wanted-0.5) > 0.05 {
+			t.Errorf("%s: expected a tone of amplitude 0.5 at %g Hz, got %f", tt.name, tt.wantFreq, wanted)
+		}
+		// Require at least 40 dB of opposite sideband rejection.
+		if rejected > wanted/100 {
+			t.Errorf("%s: poor sideband rejection: %f at %g Hz", tt.name, rejected, tt.rejectFreq)
+		}
+	}
+}
+
+func TestCWDemodulator(t *testing.T) {
+	const sampleRate = 12000
+	// A carrier 50 Hz above DC is heard at 750 Hz; one 600 Hz away is outside
+	// the 500 Hz filter.
+	audio := demodulate(NewCWDemodulator(sampleRate, 700, 500, 511), sampleRate, 50, 600)
+	if level := toneLevel(audio, sampleRate, 750); math.Abs(level-0.5) > 0.05 {
+		t.Errorf("Expected a tone of amplitude 0.5 at 750 Hz, got %f", level)
+	}
+	if level := toneLevel(audio, sampleRate, 1300); level > 0.005 {
+		t.Errorf("Expected the off-frequency carrier to be rejected, got %f at 1300 Hz", level)
+	}
+}
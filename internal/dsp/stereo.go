@@ -0,0 +1,87 @@
+package dsp
+
+import "math"
+
+const (
+	pilotFrequency = 19000.0
+	// Pilot lock fraction needed to switch to stereo, and to stay there.
+	pilotLockOn  = 0.01
+	pilotLockOff = 0.005
+)
+
+// StereoDecoder splits an FM multiplex (MPX) signal into left and right audio
+// channels. It locks a PLL to the 19 kHz pilot, regenerates the 38 kHz
+// subcarrier to recover L-R, and matrixes it with L+R. When the pilot is too
+// weak the decoder falls back to mono by fading out the L-R component.
+type StereoDecoder struct {
+	pll        *PLL
+	sumFilter  Resampler
+	diffFilter Resampler
+	deemphL    *Deemphasis
+	deemphR    *Deemphasis
+
+	stereo    bool
+	blend     float32
+	blendStep float32
+}
+
+// NewStereoDecoder creates a stereo decoder for an MPX signal sampled at
+// inputRate, producing audio at outputRate. numTaps and audioCutoff (normalized
+// to inputRate) describe the audio low-pass filters, and tau is the
+// de-emphasis time constant applied to each output channel.
+func NewStereoDecoder(inputRate, outputRate, numTaps int, audioCutoff, tau float64) *StereoDecoder {
+	return &StereoDecoder{
+		pll:        NewPLL(inputRate, pilotFrequency, 30, 100),
+		sumFilter:  NewResampler(inputRate, outputRate, numTaps, audioCutoff),
+		diffFilter: NewResampler(inputRate, outputRate, numTaps, audioCutoff),
+		deemphL:    NewDeemphasis(outputRate, tau),
+		deemphR:    NewDeemphasis(outputRate, tau),
+		// Fade between mono and stereo over roughly 50ms.
+		blendStep: float32(1 / (0.05 * float64(inputRate))),
+	}
+}
+
+// Stereo reports whether the decoder is currently locked to a pilot tone.
+func (s *StereoDecoder) Stereo() bool {
+	return s.stereo
+}
+
+// PilotLevel returns the fraction of the MPX power carried by the pilot.
+func (s *StereoDecoder) PilotLevel() float64 {
+	return s.pll.Lock()
+}
+
+// Process decodes a block of MPX samples into de-emphasized left and right
+// channels at the output rate.
+func (s *StereoDecoder) Process(mpx []float32) (left, right []float32) {
+	diff := make([]float32, len(mpx))
+	for i, x := range mpx {
+		phase := s.pll.Update(float64(x))
+
+		lock := s.pll.Lock()
+		if s.stereo && lock < pilotLockOff {
+			s.stereo = false
+		} else if !s.stereo && lock > pilotLockOn {
+			s.stereo = true
+		}
+		if s.stereo && s.blend < 1 {
+			s.blend = min(s.blend+s.blendStep, 1)
+		} else if !s.stereo && s.blend > 0 {
+			s.blend = max(s.blend-s.blendStep, 0)
+		}
+
+		// The L-R subcarrier is in phase with the doubled pilot.
+		diff[i] = x * float32(2*math.Sin(2*phase)) * s.blend
+	}
+
+	sum := s.sumFilter.Process(mpx)
+	diff = s.diffFilter.Process(diff)
+
+	left = make([]float32, len(sum))
+	right = make([]float32, len(sum))
+	for i := range sum {
+		left[i] = float32(s.deemphL.Filter(float64(sum[i] + diff[i])))
+		right[i] = float32(s.deemphR.Filter(float64(sum[i] - diff[i])))
+	}
+	return left, right
+}
@@ -0,0 +1,89 @@
+package dsp
+
+import (
+	"math"
+	"testing"
+)
+
+// generateMPX builds an FM multiplex signal at sampleRate carrying the given
+// left and right tones, with or without a 19 kHz pilot.
+func generateMPX(sampleRate, numSamples int, leftFreq, rightFreq float64, pilot bool) []float32 {
+	mpx := make([]float32, numSamples)
+	for i := range mpx {
+		t := float64(i) / float64(sampleRate)
+		var l, r float64
+		if leftFreq > 0 {
+			l = math.Sin(2 * math.Pi * leftFreq * t)
+		}
+		if rightFreq > 0 {
+			r = math.Sin(2 * math.Pi * rightFreq * t)
+		}
+		x := 0.45 * (l + r)
+		if pilot {
+			x += 0.1*math.Sin(2*math.Pi*pilotFrequency*t) + 0.45*(l-r)*math.Sin(2*math.Pi*2*pilotFrequency*t)
+		}
+		mpx[i] = float32(x)
+	}
+	return mpx
+}
+
+func rms(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// decodeStereo runs the MPX signal through a decoder in blocks and returns the
+// second half of each output channel, after the PLL has settled.
+func decodeStereo(dec *StereoDecoder, mpx []float32) (left, right []float32) {
+	const blockSize = 4096
+	for i := 0; i < len(mpx); i += blockSize {
+		end := min(i+blockSize, len(mpx))
+		l, r := dec.Process(mpx[i:end])
+		left = append(left, l...)
+		right = append(right, r...)
+	}
+	return left[len(left)/2:], right[len(right)/2:]
+}
+
+func TestStereoDecoder_Separation(t *testing.T) {
+	const inputRate = 240000
+	const outputRate = 48000
+
+	dec := NewStereoDecoder(inputRate, outputRate, 251, 15000.0/inputRate, 50e-6)
+	mpx := generateMPX(inputRate, inputRate, 1000, 0, true)
+	left, right := decodeStereo(dec, mpx)
+
+	if !dec.Stereo() {
+		t.Fatalf("Expected decoder to lock to the pilot, lock level %f", dec.PilotLevel())
+	}
+
+	leftLevel, rightLevel := rms(left), rms(right)
+	if leftLevel < 0.3 {
+		t.Errorf("Expected left channel to carry the tone, got RMS %f", leftLevel)
+	}
+	// Require at least 20 dB of channel separation.
+	if rightLevel > leftLevel/10 {
+		t.Errorf("Poor stereo separation: left RMS %f, right RMS %f", leftLevel, rightLevel)
+	}
+}
+
+func TestStereoDecoder_MonoFallback(t *testing.T) {
+	const inputRate = 240000
+	const outputRate = 48000
+
+	dec := NewStereoDecoder(inputRate, outputRate, 251, 15000.0/inputRate, 50e-6)
+	mpx := generateMPX(inputRate, inputRate/2, 1000, 0, false)
+	left, right := decodeStereo(dec, mpx)
+
+	if dec.Stereo() {
+		t.Fatalf("Expected mono without a pilot, lock level %f", dec.PilotLevel())
+	}
+	for i := range left {
+		if !almostEqual(left[i], right[i]) {
+			t.Fatalf("Sample %d: expected identical channels in mono, got %f and %f", i, left[i], right[i])
+		}
+	}
+}
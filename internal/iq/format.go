@@ -0,0 +1,198 @@
+// Package iq reads complex baseband (IQ) samples from recordings and streams,
+// converting every supported sample format to normalized complex64 samples.
+package iq
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the encoding of interleaved I/Q samples in a raw recording.
+type Format int
+
+const (
+	// CS16 is signed 16-bit little-endian, as written by most SDR software.
+	CS16 Format = iota
+	// CU8 is unsigned 8-bit with a 127.5 offset, as written by rtl_sdr.
+	CU8
+	// CS8 is signed 8-bit, as written by hackrf_transfer.
+	CS8
+	// CF32 is 32-bit little-endian IEEE float.
+	CF32
+	// CF64 is 64-bit little-endian IEEE float.
+	CF64
+	// CS24 is signed 24-bit little-endian, as found in 24-bit IQ WAV files.
+	CS24
+	// CS32 is signed 32-bit little-endian.
+	CS32
+)
+
+var formatNames = map[Format]string{
+	CS16: "cs16",
+	CU8:  "cu8",
+	CS8:  "cs8",
+	CF32: "cf32",
+	CF64: "cf64",
+	CS24: "cs24",
+	CS32: "cs32",
+}
+
+// String returns the short name of the format, such as "cu8".
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// FormatError reports a sample format that cannot be decoded.
+type FormatError struct {
+	Source    string   // What described the format, such as "SigMF datatype".
+	Format    string   // The unsupported format.
+	Supported []string // The formats that would have been accepted, if known.
+}
+
+func (e *FormatError) Error() string {
+	msg := fmt.Sprintf("unsupported %s %q", e.Source, e.Format)
+	if len(e.Supported) > 0 {
+		msg += fmt.Sprintf(" (want one of %s)", strings.Join(e.Supported, ", "))
+	}
+	return msg
+}
+
+// ParseFormat returns the format with the given short name, such as "cu8".
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for f, n := range formatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, &FormatError{
+		Source:    "IQ sample format",
+		Format:    name,
+		Supported: []string{"cu8", "cs8", "cs16", "cs24", "cs32", "cf32", "cf64"},
+	}
+}
+
+// FormatFromPath guesses the sample format from a file extension, such as
+// "capture.cu8". It reports false for extensions it does not recognize.
+func FormatFromPath(path string) (Format, bool) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	switch ext {
+	case "iq", "raw":
+		return CS16, true
+	case "bin":
+		return CU8, true
+	}
+	f, err := ParseFormat(ext)
+	return f, err == nil
+}
+
+// SampleSize returns the number of bytes in one complex (I+Q) sample.
+func (f Format) SampleSize() int {
+	switch f {
+	case CU8, CS8:
+		return 2
+	case CS16:
+		return 4
+	case CS24:
+		return 6
+	case CS32, CF32:
+		return 8
+	case CF64:
+		return 16
+	}
+	return 0
+}
+
+// Decode converts the complete samples in src to normalized complex samples in
+// dst and returns the number of samples written. dst must have room for
+// len(src)/f.SampleSize() samples.
+func (f Format) Decode(dst []complex64, src []byte) int {
+	n := len(src) / f.SampleSize()
+	for i := 0; i < n; i++ {
+		switch f {
+		case CU8:
+			dst[i] = complex((float32(src[2*i])-127.5)/127.5, (float32(src[2*i+1])-127.5)/127.5)
+		case CS8:
+			dst[i] = complex(float32(int8(src[2*i]))/128, float32(int8(src[2*i+1]))/128)
+		case CS16:
+			re := int16(binary.LittleEndian.Uint16(src[4*i:]))
+			im := int16(binary.LittleEndian.Uint16(src[4*i+2:]))
+			dst[i] = complex(float32(re)/32768, float32(im)/32768)
+		case CS24:
+			// Shift the three bytes into the top of an int32 to sign-extend them.
+			re := int32(uint32(src[6*i])<<8|uint32(src[6*i+1])<<16|uint32(src[6*i+2])<<24) >> 8
+			im := int32(uint32(src[6*i+3])<<8|uint32(src[6*i+4])<<16|uint32(src[6*i+5])<<24) >> 8
+			dst[i] = complex(float32(re)/(1<<23), float32(im)/(1<<23))
+		case CS32:
+			re := int32(binary.LittleEndian.Uint32(src[8*i:]))
+			im := int32(binary.LittleEndian.Uint32(src[8*i+4:]))
+			dst[i] = complex(float32(float64(re)/(1<<31)), float32(float64(im)/(1<<31)))
+		case CF32:
+			re := math.Float32frombits(binary.LittleEndian.Uint32(src[8*i:]))
+			im := math.Float32frombits(binary.LittleEndian.Uint32(src[8*i+4:]))
+			dst[i] = complex(re, im)
+		case CF64:
+			re := math.Float64frombits(binary.LittleEndian.Uint64(src[16*i:]))
+			im := math.Float64frombits(binary.LittleEndian.Uint64(src[16*i+8:]))
+			dst[i] = complex(float32(re), float32(im))
+		}
+	}
+	return n
+}
+
+// Reader decodes a stream of raw IQ samples in a given format.
+type Reader struct {
+	r      io.Reader
+	format Format
+	buf    []byte
+	n      int // Bytes of an incomplete sample carried over in buf.
+}
+
+// NewReader creates a Reader decoding samples of the given format from r.
+func NewReader(r io.Reader, format Format) *Reader {
+	return &Reader{r: r, format: format}
+}
+
+// Format returns the sample format being decoded.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Read decodes up to len(dst) samples into dst. Partial samples at the end of
+// an underlying read are kept for the next call. It returns io.EOF once the
+// stream is exhausted; a trailing incomplete sample is discarded.
+func (r *Reader) Read(dst []complex64) (int, error) {
+	if len(dst) == 0 {
+		return 0, nil
+	}
+	size := r.format.SampleSize()
+	if need := len(dst) * size; len(r.buf) < need {
+		buf := make([]byte, need)
+		copy(buf, r.buf[:r.n])
+		r.buf = buf
+	}
+
+	for {
+		m, err := r.r.Read(r.buf[r.n : len(dst)*size])
+		r.n += m
+		samples := r.format.Decode(dst, r.buf[:r.n])
+		if samples > 0 {
+			// Move the incomplete sample, if any, to the front.
+			r.n = copy(r.buf, r.buf[samples*size:r.n])
+			if err == io.EOF {
+				err = nil
+			}
+			return samples, err
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package iq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+func TestFormat_Decode(t *testing.T) {
+	f32 := func(v float32) []byte { return binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)) }
+	f64 := func(v float64) []byte { return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)) }
+
+	tests := []struct {
+		format Format
+		src    []byte
+		want   complex64
+	}{
+		{CU8, []byte{255, 0}, complex(1, -1)},
+		{CS8, []byte{0x40, 0xC0}, complex(0.5, -0.5)},
+		{CS16, []byte{0x00, 0x40, 0x00, 0xC0}, complex(0.5, -0.5)},
+		{CS24, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0xC0}, complex(0.5, -0.5)},
+		{CS32, []byte{0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0xC0}, complex(0.5, -0.5)},
+		{CF32, append(f32(0.25), f32(-0.75)...), complex(0.25, -0.75)},
+		{CF64, append(f64(0.25), f64(-0.75)...), complex(0.25, -0.75)},
+	}
+	for _, tt := range tests {
+		if len(tt.src) != tt.format.SampleSize() {
+			t.Fatalf("%v: test sample has %d bytes, format expects %d", tt.format, len(tt.src), tt.format.SampleSize())
+		}
+		dst := make([]complex64, 1)
+		if n := tt.format.Decode(dst, tt.src); n != 1 || dst[0] != tt.want {
+			t.Errorf("%v: expected %v, got %v (n=%d)", tt.format, tt.want, dst[0], n)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for f, name := range formatNames {
+		got, err := ParseFormat(name)
+		if err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %v, %v; expected %v", name, got, err, f)
+		}
+	}
+	if _, err := ParseFormat("cs12"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+
+	if f, ok := FormatFromPath("/tmp/capture.CU8"); !ok || f != CU8 {
+		t.Errorf("Expected cu8 from file extension, got %v (ok=%v)", f, ok)
+	}
+	if _, ok := FormatFromPath("capture.dat"); ok {
+		t.Errorf("Expected unknown extension not to match a format")
+	}
+}
+
+// TestReader_PartialSamples checks that samples split across underlying reads
+// are reassembled.
+func TestReader_PartialSamples(t *testing.T) {
+	var raw []byte
+	for i := 0; i < 100; i++ {
+		raw = binary.LittleEndian.AppendUint16(raw, uint16(int16(i*100)))
+		raw = binary.LittleEndian.AppendUint16(raw, uint16(int16(-i*100)))
+	}
+	// A trailing incomplete sample must be dropped.
+	raw = append(raw, 0x01)
+
+	r := NewReader(iotest.OneByteReader(bytes.NewReader(raw)), CS16)
+	var got []complex64
+	buf := make([]complex64, 7)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if len(got) != 100 {
+		t.Fatalf("Expected 100 samples, got %d", len(got))
+	}
+	for i, s := range got {
+		want := complex(float32(i*100)/32768, float32(-i*100)/32768)
+		if s != want {
+			t.Fatalf("Sample %d: expected %v, got %v", i, want, s)
+		}
+	}
+}
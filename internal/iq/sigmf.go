@@ -0,0 +1,192 @@
+package iq
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SigMF file extensions.
+const (
+	SigMFMetaExt = ".sigmf-meta"
+	SigMFDataExt = ".sigmf-data"
+)
+
+// sigmfFormats maps SigMF core:datatype values to sample formats.
+var sigmfFormats = map[string]Format{
+	"ci16_le": CS16,
+	"cu8":     CU8,
+	"ci8":     CS8,
+	"ci32_le": CS32,
+	"cf32_le": CF32,
+	"cf64_le": CF64,
+}
+
+// SigMFGlobal holds the fields of the SigMF global object that we use.
+type SigMFGlobal struct {
+	Datatype    string  `json:"core:datatype"`
+	SampleRate  float64 `json:"core:sample_rate,omitempty"`
+	Version     string  `json:"core:version,omitempty"`
+	Description string  `json:"core:description,omitempty"`
+}
+
+// SigMFCapture describes a segment of the recording.
+type SigMFCapture struct {
+	SampleStart uint64  `json:"core:sample_start"`
+	Frequency   float64 `json:"core:frequency,omitempty"`
+	Datetime    string  `json:"core:datetime,omitempty"`
+}
+
+// SigMFAnnotation labels a range of samples in the recording.
+type SigMFAnnotation struct {
+	SampleStart   uint64  `json:"core:sample_start"`
+	SampleCount   uint64  `json:"core:sample_count,omitempty"`
+	FreqLowerEdge float64 `json:"core:freq_lower_edge,omitempty"`
+	FreqUpperEdge float64 `json:"core:freq_upper_edge,omitempty"`
+	Label         string  `json:"core:label,omitempty"`
+	Comment       string  `json:"core:comment,omitempty"`
+	Generator     string  `json:"core:generator,omitempty"`
+}
+
+// SigMF is a SigMF recording: a JSON metadata file describing a raw sample
+// data file. Fields of the metadata that are not modelled here, including
+// those of the annotations read from the file, are preserved when the
+// metadata is saved.
+type SigMF struct {
+	MetaPath    string
+	DataPath    string
+	Global      SigMFGlobal
+	Captures    []SigMFCapture
+	Annotations []SigMFAnnotation // Those read from the file, then those added by Annotate.
+
+	raw            map[string]json.RawMessage
+	rawAnnotations []json.RawMessage // The annotations read from the file, as they were.
+}
+
+// IsSigMF reports whether path names a SigMF metadata or data file.
+func IsSigMF(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == SigMFMetaExt || ext == SigMFDataExt
+}
+
+// OpenSigMF reads the metadata of the SigMF recording that path, either the
+// metadata or the data file, belongs to.
+func OpenSigMF(path string) (*SigMF, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	s := &SigMF{
+		MetaPath: base + SigMFMetaExt,
+		DataPath: base + SigMFDataExt,
+	}
+
+	data, err := os.ReadFile(s.MetaPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.MetaPath, err)
+	}
+	for key, dst := range map[string]any{"global": &s.Global, "captures": &s.Captures, "annotations": &s.rawAnnotations} {
+		if field, ok := s.raw[key]; ok {
+			if err := json.Unmarshal(field, dst); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", s.MetaPath, key, err)
+			}
+		}
+	}
+	s.Annotations = make([]SigMFAnnotation, len(s.rawAnnotations))
+	for i, field := range s.rawAnnotations {
+		if err := json.Unmarshal(field, &s.Annotations[i]); err != nil {
+			return nil, fmt.Errorf("%s: annotations: %w", s.MetaPath, err)
+		}
+	}
+	return s, nil
+}
+
+// Format returns the sample format of the data file.
+func (s *SigMF) Format() (Format, error) {
+	if f, ok := sigmfFormats[s.Global.Datatype]; ok {
+		return f, nil
+	}
+	supported := make([]string, 0, len(sigmfFormats))
+	for datatype := range sigmfFormats {
+		supported = append(supported, datatype)
+	}
+	slices.Sort(supported)
+	return 0, &FormatError{Source: "SigMF datatype", Format: s.Global.Datatype, Supported: supported}
+}
+
+// SampleRate returns the sample rate of the recording in Hz, or zero when the
+// metadata does not specify it.
+func (s *SigMF) SampleRate() int {
+	return int(s.Global.SampleRate)
+}
+
+// CenterFrequency returns the centre frequency of the first capture in Hz, or
+// zero when the metadata does not specify it.
+func (s *SigMF) CenterFrequency() float64 {
+	if len(s.Captures) == 0 {
+		return 0
+	}
+	return s.Captures[0].Frequency
+}
+
+// Annotate adds an annotation to the recording. It is only written to disk
+// by Save.
+func (s *SigMF) Annotate(a SigMFAnnotation) {
+	s.Annotations = append(s.Annotations, a)
+}
+
+// Save writes the metadata, including any new annotations, back to MetaPath.
+// The annotations read from the file are written as they were, and all of
+// them are sorted by their first sample, as SigMF requires.
+func (s *SigMF) Save() error {
+	type annotation struct {
+		start uint64
+		json  json.RawMessage
+	}
+	all := make([]annotation, len(s.Annotations))
+	for i, a := range s.Annotations {
+		all[i].start = a.SampleStart
+		if i < len(s.rawAnnotations) {
+			all[i].json = s.rawAnnotations[i]
+			continue
+		}
+		var err error
+		if all[i].json, err = json.Marshal(a); err != nil {
+			return err
+		}
+	}
+	// A stable sort keeps annotations that start together in the order
+	// they were made.
+	slices.SortStableFunc(all, func(a, b annotation) int { return cmp.Compare(a.start, b.start) })
+
+	sorted := make([]json.RawMessage, len(all))
+	for i, a := range all {
+		sorted[i] = a.json
+	}
+	annotations, err := json.Marshal(sorted)
+	if err != nil {
+		return err
+	}
+
+	raw := make(map[string]json.RawMessage, len(s.raw)+1)
+	for key, value := range s.raw {
+		raw[key] = value
+	}
+	raw["annotations"] = annotations
+
+	data, err := json.MarshalIndent(raw, "", "    ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interrupted save cannot corrupt
+	// the original metadata.
+	tmp := s.MetaPath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.MetaPath)
+}
@@ -0,0 +1,88 @@
+package iq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testMeta = `{
+    "global": {
+        "core:datatype": "cu8",
+        "core:sample_rate": 2048000,
+        "core:version": "1.0.0",
+        "core:hw": "RTL-SDR v3"
+    },
+    "captures": [
+        {"core:sample_start": 0, "core:frequency": 98500000}
+    ],
+    "annotations": [
+        {"core:sample_start": 10000, "core:sample_count": 500, "core:label": "burst", "myapp:snr": 12.5}
+    ]
+}`
+
+func TestSigMF_ReadAndAnnotate(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "capture")
+	if err := os.WriteFile(base+SigMFMetaExt, []byte(testMeta), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsSigMF(base + SigMFDataExt) {
+		t.Fatalf("Expected %s to be recognized as SigMF", base+SigMFDataExt)
+	}
+	s, err := OpenSigMF(base + SigMFDataExt)
+	if err != nil {
+		t.Fatalf("OpenSigMF failed: %v", err)
+	}
+
+	if f, err := s.Format(); err != nil || f != CU8 {
+		t.Errorf("Expected cu8 datatype, got %v (%v)", f, err)
+	}
+	if s.SampleRate() != 2_048_000 {
+		t.Errorf("Expected sample rate 2048000, got %d", s.SampleRate())
+	}
+	if s.CenterFrequency() != 98.5e6 {
+		t.Errorf("Expected centre frequency 98.5 MHz, got %f", s.CenterFrequency())
+	}
+	if len(s.Annotations) != 1 || s.Annotations[0].Label != "burst" {
+		t.Fatalf("Expected the existing annotation to be read, got %+v", s.Annotations)
+	}
+
+	s.Annotate(SigMFAnnotation{SampleStart: 12288, SampleCount: 4096, Label: "RDS", Comment: `RT: "Now playing"`})
+	s.Annotate(SigMFAnnotation{SampleStart: 4096, SampleCount: 4096, Label: "RDS", Comment: `PS: "TESTFM"`})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened, err := OpenSigMF(base + SigMFMetaExt)
+	if err != nil {
+		t.Fatalf("Reopening failed: %v", err)
+	}
+	var starts []uint64
+	for _, a := range reopened.Annotations {
+		starts = append(starts, a.SampleStart)
+	}
+	if !slices.Equal(starts, []uint64{4096, 10000, 12288}) || reopened.Annotations[0].Comment != `PS: "TESTFM"` {
+		t.Errorf("Expected the new annotations to be saved in sample order, got %+v", reopened.Annotations)
+	}
+	saved, _ := os.ReadFile(base + SigMFMetaExt)
+	if !strings.Contains(string(saved), `"core:hw": "RTL-SDR v3"`) {
+		t.Errorf("Expected unmodelled global fields to be preserved, got:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), `"myapp:snr": 12.5`) {
+		t.Errorf("Expected unmodelled annotation fields to be preserved, got:\n%s", saved)
+	}
+}
+
+func TestSigMF_UnsupportedDatatype(t *testing.T) {
+	s := &SigMF{Global: SigMFGlobal{Datatype: "ci16_be"}}
+	_, err := s.Format()
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Format != "ci16_be" {
+		t.Errorf("Expected a FormatError for a big-endian datatype, got %v", err)
+	}
+}
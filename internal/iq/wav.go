@@ -0,0 +1,191 @@
+package iq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotWAV is returned by ReadWAVHeader when the input is not a RIFF/RF64
+// WAVE file.
+var ErrNotWAV = errors.New("not a WAV file")
+
+// maxWAVChunkSize is the largest fmt, ds64 or auxi chunk ReadWAVHeader will
+// read; real ones are well under 1 KiB.
+const maxWAVChunkSize = 64 << 10
+
+// WAV format tags.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// WAVHeader describes an IQ recording stored as a two-channel WAV file, such
+// as those written by SDR# and HDSDR.
+type WAVHeader struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Float         bool  // Samples are IEEE float rather than integer PCM.
+	DataSize      int64 // Bytes of sample data, or -1 when unknown (e.g. a stream).
+
+	// Fields from the SDR#/HDSDR auxi chunk; zero when it is missing.
+	CenterFrequency float64
+	StartTime       time.Time
+	StopTime        time.Time
+}
+
+// Format returns the IQ sample format of the data chunk.
+func (h *WAVHeader) Format() (Format, error) {
+	if h.Channels != 2 {
+		return 0, &FormatError{Source: "IQ WAV layout", Format: fmt.Sprintf("%d channels", h.Channels), Supported: []string{"2 channels"}}
+	}
+	switch {
+	case h.Float && h.BitsPerSample == 32:
+		return CF32, nil
+	case h.Float && h.BitsPerSample == 64:
+		return CF64, nil
+	case h.Float:
+	case h.BitsPerSample == 8:
+		// 8-bit WAV samples are unsigned, like rtl_sdr output.
+		return CU8, nil
+	case h.BitsPerSample == 16:
+		return CS16, nil
+	case h.BitsPerSample == 24:
+		return CS24, nil
+	case h.BitsPerSample == 32:
+		return CS32, nil
+	}
+	kind := "integer"
+	if h.Float {
+		kind = "float"
+	}
+	return 0, &FormatError{Source: "WAV sample format", Format: fmt.Sprintf("%d-bit %s", h.BitsPerSample, kind)}
+}
+
+// ReadWAVHeader parses the chunks of a RIFF or RF64 WAVE file up to the start
+// of the sample data, leaving r positioned at the first sample. It returns
+// ErrNotWAV if r does not start with a WAVE header.
+func ReadWAVHeader(r io.Reader) (*WAVHeader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotWAV
+		}
+		return nil, err
+	}
+	id := string(riff[0:4])
+	if (id != "RIFF" && id != "RF64") || string(riff[8:12]) != "WAVE" {
+		return nil, ErrNotWAV
+	}
+
+	h := &WAVHeader{DataSize: -1}
+	var haveFormat bool
+	var ds64DataSize int64 = -1
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("reading WAV chunk header: %w", err)
+		}
+		chunkID := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		if chunkID == "data" {
+			if !haveFormat {
+				return nil, errors.New("WAV data chunk before fmt chunk")
+			}
+			switch {
+			case size == 0xFFFFFFFF && ds64DataSize >= 0:
+				// RF64 stores the real size in the ds64 chunk.
+				h.DataSize = ds64DataSize
+			case size != 0xFFFFFFFF && size != 0:
+				h.DataSize = size
+			}
+			return h, nil
+		}
+
+		padded := size + size%2 // Chunks are padded to an even length.
+		if chunkID != "fmt " && chunkID != "ds64" && chunkID != "auxi" {
+			if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+				return nil, fmt.Errorf("skipping WAV %q chunk: %w", chunkID, err)
+			}
+			continue
+		}
+		if size > maxWAVChunkSize {
+			return nil, fmt.Errorf("WAV %q chunk of %d bytes is too large", chunkID, size)
+		}
+		body := make([]byte, padded)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("reading WAV %q chunk: %w", chunkID, err)
+		}
+		body = body[:size]
+
+		switch chunkID {
+		case "fmt ":
+			if err := h.parseFormat(body); err != nil {
+				return nil, err
+			}
+			haveFormat = true
+		case "ds64":
+			if len(body) < 16 {
+				return nil, errors.New("WAV ds64 chunk too short")
+			}
+			ds64DataSize = int64(binary.LittleEndian.Uint64(body[8:16]))
+		case "auxi":
+			h.parseAuxi(body)
+		}
+	}
+}
+
+// parseFormat reads the fmt chunk.
+func (h *WAVHeader) parseFormat(body []byte) error {
+	if len(body) < 16 {
+		return errors.New("WAV fmt chunk too short")
+	}
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	h.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+	h.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+	h.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+
+	if tag == wavFormatExtensible {
+		// The real format tag is the start of the sub-format GUID.
+		if len(body) < 26 {
+			return errors.New("WAV extensible fmt chunk too short")
+		}
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+	switch tag {
+	case wavFormatPCM:
+	case wavFormatFloat:
+		h.Float = true
+	default:
+		return &FormatError{Source: "WAV format tag", Format: fmt.Sprintf("%#x", tag), Supported: []string{"PCM", "IEEE float"}}
+	}
+	return nil
+}
+
+// parseAuxi reads the centre frequency and capture times from the auxi chunk
+// written by SDR# and HDSDR. It starts with two Windows SYSTEMTIME structures
+// followed by the centre frequency in Hz.
+func (h *WAVHeader) parseAuxi(body []byte) {
+	if len(body) < 36 {
+		return
+	}
+	h.StartTime = systemTime(body[0:16])
+	h.StopTime = systemTime(body[16:32])
+	h.CenterFrequency = float64(binary.LittleEndian.Uint32(body[32:36]))
+}
+
+// systemTime decodes a Windows SYSTEMTIME structure, returning the zero time
+// if it is unset.
+func systemTime(b []byte) time.Time {
+	field := func(i int) int { return int(binary.LittleEndian.Uint16(b[2*i:])) }
+	if field(0) == 0 {
+		return time.Time{}
+	}
+	// Fields: year, month, day of week, day, hour, minute, second, milliseconds.
+	return time.Date(field(0), time.Month(field(1)), field(3), field(4), field(5), field(6), field(7)*int(time.Millisecond), time.UTC)
+}
@@ -0,0 +1,132 @@
+package iq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// chunk encodes a RIFF chunk, padded to an even length.
+func chunk(id string, body []byte) []byte {
+	b := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// fmtChunk encodes a two-channel fmt chunk body.
+func fmtChunk(tag uint16, rate, bits int) []byte {
+	le := binary.LittleEndian
+	b := le.AppendUint16(nil, tag)
+	b = le.AppendUint16(b, 2)
+	b = le.AppendUint32(b, uint32(rate))
+	b = le.AppendUint32(b, uint32(rate*bits/4))
+	b = le.AppendUint16(b, uint16(bits/4))
+	return le.AppendUint16(b, uint16(bits))
+}
+
+func TestReadWAVHeader_Auxi(t *testing.T) {
+	le := binary.LittleEndian
+	var auxi []byte
+	for _, st := range [][8]uint16{{2024, 3, 5, 15, 12, 30, 45, 250}, {2024, 3, 5, 15, 12, 31, 0, 0}} {
+		for _, v := range st {
+			auxi = le.AppendUint16(auxi, v)
+		}
+	}
+	auxi = le.AppendUint32(auxi, 98_500_000)
+	auxi = append(auxi, make([]byte, 24)...) // Remaining auxi fields are ignored.
+
+	samples := []byte{0x00, 0x40, 0x00, 0xC0}
+	var file []byte
+	file = append(file, chunk("fmt ", fmtChunk(wavFormatPCM, 2_048_000, 16))...)
+	file = append(file, chunk("auxi", auxi)...)
+	file = append(file, chunk("data", samples)...)
+	file = append([]byte("RIFF\x00\x00\x00\x00WAVE"), file...)
+
+	r := bytes.NewReader(file)
+	h, err := ReadWAVHeader(r)
+	if err != nil {
+		t.Fatalf("ReadWAVHeader failed: %v", err)
+	}
+	if h.SampleRate != 2_048_000 || h.CenterFrequency != 98.5e6 || h.DataSize != 4 {
+		t.Errorf("Unexpected header: %+v", h)
+	}
+	if want := time.Date(2024, 3, 15, 12, 30, 45, 250_000_000, time.UTC); !h.StartTime.Equal(want) {
+		t.Errorf("Expected start time %v, got %v", want, h.StartTime)
+	}
+	if f, err := h.Format(); err != nil || f != CS16 {
+		t.Errorf("Expected cs16, got %v (%v)", f, err)
+	}
+	if rest, _ := io.ReadAll(r); !bytes.Equal(rest, samples) {
+		t.Errorf("Expected reader to be left at the samples, got %v", rest)
+	}
+}
+
+func TestReadWAVHeader_RF64Float(t *testing.T) {
+	le := binary.LittleEndian
+	const dataSize = 5_000_000_000
+	ds64 := le.AppendUint64(nil, dataSize+100)
+	ds64 = le.AppendUint64(ds64, dataSize)
+	ds64 = le.AppendUint64(ds64, dataSize/8)
+	ds64 = le.AppendUint32(ds64, 0)
+
+	// WAVE_FORMAT_EXTENSIBLE with the IEEE float sub-format.
+	format := fmtChunk(wavFormatExtensible, 192_000, 32)
+	format = le.AppendUint16(format, 22)
+	format = le.AppendUint16(format, 32)
+	format = le.AppendUint32(format, 0)
+	format = le.AppendUint16(format, wavFormatFloat)
+	format = append(format, make([]byte, 14)...)
+
+	var file []byte
+	file = append(file, "RF64\xFF\xFF\xFF\xFFWAVE"...)
+	file = append(file, chunk("ds64", ds64)...)
+	file = append(file, chunk("fmt ", format)...)
+	file = append(file, "data\xFF\xFF\xFF\xFF"...)
+
+	h, err := ReadWAVHeader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("ReadWAVHeader failed: %v", err)
+	}
+	if h.DataSize != dataSize {
+		t.Errorf("Expected data size %d from ds64, got %d", int64(dataSize), h.DataSize)
+	}
+	if f, err := h.Format(); err != nil || f != CF32 {
+		t.Errorf("Expected cf32, got %v (%v)", f, err)
+	}
+}
+
+func TestReadWAVHeader_NotWAV(t *testing.T) {
+	_, err := ReadWAVHeader(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}))
+	if !errors.Is(err, ErrNotWAV) {
+		t.Errorf("Expected ErrNotWAV, got %v", err)
+	}
+}
+
+func TestReadWAVHeader_LargeChunks(t *testing.T) {
+	header := append([]byte("RIFF\x00\x00\x00\x00WAVE"), chunk("fmt ", fmtChunk(wavFormatPCM, 48_000, 16))...)
+
+	// Odd-sized chunks the reader doesn't use are skipped with their padding.
+	file := append(bytes.Clone(header), chunk("LIST", []byte("INFOISFT\x03\x00\x00\x00SDR"))...)
+	file = append(file, chunk("data", []byte{1, 2, 3, 4})...)
+	r := bytes.NewReader(file)
+	if h, err := ReadWAVHeader(r); err != nil || h.DataSize != 4 {
+		t.Fatalf("Expected the LIST chunk to be skipped, got %+v (%v)", h, err)
+	}
+	if rest, _ := io.ReadAll(r); !bytes.Equal(rest, []byte{1, 2, 3, 4}) {
+		t.Errorf("Expected reader to be left at the samples, got %v", rest)
+	}
+
+	// A truncated file claiming a 4 GiB chunk fails without buffering it.
+	for _, id := range []string{"junk", "auxi"} {
+		file := append(bytes.Clone(header), id+"\xF0\xFF\xFF\xFF"...)
+		if _, err := ReadWAVHeader(bytes.NewReader(file)); err == nil {
+			t.Errorf("Expected an error for a huge %s chunk", id)
+		}
+	}
+}
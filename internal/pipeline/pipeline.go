@@ -0,0 +1,47 @@
+// Package pipeline runs the concurrent stages of a decoding pipeline and
+// collects their errors.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group runs named pipeline stages in goroutines. The first stage to fail
+// cancels the context shared by all stages, and its error, prefixed with the
+// stage name, is returned by Wait. It works like errgroup.Group.
+type Group struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// WithContext returns a Group and a context derived from ctx that is
+// cancelled when a stage fails or Wait returns.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go runs stage in a new goroutine.
+func (g *Group) Go(name string, stage func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := stage(); err != nil {
+			g.once.Do(func() {
+				g.err = fmt.Errorf("%s: %w", name, err)
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until every stage has returned, then returns the first error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
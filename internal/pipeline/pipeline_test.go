@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGroup_FirstErrorCancels(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	cause := errors.New("read failed")
+
+	g.Go("reader", func() error { return cause })
+	g.Go("processor", func() error {
+		<-ctx.Done() // Only returns once the reader's failure cancels it.
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	if !errors.Is(err, cause) {
+		t.Fatalf("Expected the reader's error, but got %v", err)
+	}
+	if err.Error() != "reader: read failed" {
+		t.Errorf("Expected the error to name the stage, but got %q", err)
+	}
+}
+
+func TestGroup_Success(t *testing.T) {
+	g, ctx := WithContext(context.Background())
+	g.Go("a", func() error { return nil })
+	g.Go("b", func() error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected no error, but got %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("Expected the context to be cancelled after Wait")
+	}
+}
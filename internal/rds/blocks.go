@@ -0,0 +1,197 @@
+package rds
+
+// Block offsets as defined by IEC 62106. Offset C' replaces C in the third
+// block of version B groups.
+const (
+	offsetA = iota
+	offsetB
+	offsetC
+	offsetCPrime
+	offsetD
+	numOffsets
+)
+
+const (
+	blockLength   = 26
+	checkPoly     = 0x5B9 // g(x) = x^10 + x^8 + x^7 + x^5 + x^4 + x^3 + 1
+	maxBurstError = 2     // Longest burst error we attempt to correct.
+	maxBadBlocks  = 10    // Consecutive uncorrectable blocks before losing sync.
+)
+
+var offsetWords = [numOffsets]uint16{0x0FC, 0x198, 0x168, 0x350, 0x1B4}
+
+// blockPosition maps an offset to the index of its block within a group.
+var blockPosition = [numOffsets]int{0, 1, 2, 2, 3}
+
+// group holds the four blocks of an RDS group. Blocks that could not be
+// received or corrected are marked as invalid.
+type group struct {
+	blocks [4]uint16
+	valid  [4]bool
+	// versionB is set when the third block carried offset C'.
+	versionB bool
+}
+
+// checkword computes the 10-bit CRC of a 16-bit information word.
+func checkword(data uint16) uint16 {
+	reg := uint32(data) << 10
+	for i := blockLength - 1; i >= 10; i-- {
+		if reg&(1<<i) != 0 {
+			reg ^= checkPoly << (i - 10)
+		}
+	}
+	return uint16(reg & 0x3FF)
+}
+
+// syndrome returns the syndrome of a 26-bit block received with the given
+// offset. A zero syndrome means the block is error free.
+func syndrome(block uint32, offset int) uint16 {
+	data := uint16(block >> 10)
+	check := uint16(block & 0x3FF)
+	return checkword(data) ^ check ^ offsetWords[offset]
+}
+
+// buildCorrectionTable maps the syndrome of every correctable burst error to
+// the error pattern that produces it.
+func buildCorrectionTable() map[uint16]uint32 {
+	table := make(map[uint16]uint32)
+	for length := 1; length <= maxBurstError; length++ {
+		// Bursts start and end with an error bit, anything may lie between.
+		for inner := uint32(0); inner < 1<<max(length-2, 0); inner++ {
+			pattern := uint32(1)
+			if length > 1 {
+				pattern = 1<<(length-1) | inner<<1 | 1
+			}
+			for shift := 0; shift <= blockLength-length; shift++ {
+				e := pattern << shift
+				s := checkword(uint16(e>>10)) ^ uint16(e&0x3FF)
+				if _, ok := table[s]; !ok {
+					table[s] = e
+				}
+			}
+		}
+	}
+	return table
+}
+
+// blockSync aligns the bit stream to RDS block boundaries and assembles the
+// received blocks into groups.
+type blockSync struct {
+	correction map[uint16]uint32
+
+	reg      uint32
+	bitIndex int64
+
+	// Acquisition state: the last block found with a valid syndrome.
+	lastOffset int
+	lastIndex  int64
+
+	synced    bool
+	bitCount  int
+	expected  int // Position of the next block within the group.
+	badBlocks int
+	current   group
+}
+
+func newBlockSync() *blockSync {
+	return &blockSync{
+		correction: buildCorrectionTable(),
+		lastIndex:  -1,
+	}
+}
+
+// Synced reports whether the block boundaries are currently known.
+func (s *blockSync) Synced() bool {
+	return s.synced
+}
+
+// push adds one bit to the stream. It returns a group each time the last
+// block of a group has been received.
+func (s *blockSync) push(bit byte) (group, bool) {
+	s.reg = (s.reg<<1 | uint32(bit&1)) & (1<<blockLength - 1)
+	s.bitIndex++
+
+	if !s.synced {
+		s.acquire()
+		return group{}, false
+	}
+
+	s.bitCount++
+	if s.bitCount < blockLength {
+		return group{}, false
+	}
+	s.bitCount = 0
+
+	pos := s.expected
+	s.expected = (s.expected + 1) % 4
+	data, offset, ok := s.decode(pos)
+	if ok {
+		s.badBlocks = 0
+		s.current.blocks[pos] = data
+		s.current.valid[pos] = true
+		s.current.versionB = s.current.versionB || offset == offsetCPrime
+	} else {
+		s.badBlocks++
+		if s.badBlocks > maxBadBlocks {
+			s.synced = false
+			s.lastIndex = -1
+		}
+	}
+
+	if pos != 3 {
+		return group{}, false
+	}
+	g := s.current
+	s.current = group{}
+	return g, true
+}
+
+// acquire searches for two consecutive error-free blocks with offsets in the
+// expected order, which establishes block synchronization.
+func (s *blockSync) acquire() {
+	for offset := range numOffsets {
+		if syndrome(s.reg, offset) != 0 {
+			continue
+		}
+		if s.lastIndex >= 0 && s.bitIndex-s.lastIndex == blockLength &&
+			(blockPosition[s.lastOffset]+1)%4 == blockPosition[offset] {
+			s.synced = true
+			s.bitCount = 0
+			s.badBlocks = 0
+			s.expected = (blockPosition[offset] + 1) % 4
+			s.current = group{}
+			pos := blockPosition[offset]
+			s.current.blocks[pos] = uint16(s.reg >> 10)
+			s.current.valid[pos] = true
+			s.current.versionB = offset == offsetCPrime
+			return
+		}
+		s.lastOffset = offset
+		s.lastIndex = s.bitIndex
+		return
+	}
+}
+
+// decode checks the block in the shift register against the offsets allowed
+// at pos, correcting burst errors where possible.
+func (s *blockSync) decode(pos int) (uint16, int, bool) {
+	offsets := []int{pos}
+	switch pos {
+	case 2:
+		offsets = []int{offsetC, offsetCPrime}
+	case 3:
+		offsets = []int{offsetD}
+	}
+
+	for _, offset := range offsets {
+		if syndrome(s.reg, offset) == 0 {
+			return uint16(s.reg >> 10), offset, true
+		}
+	}
+	for _, offset := range offsets {
+		if e, ok := s.correction[syndrome(s.reg, offset)]; ok {
+			return uint16((s.reg ^ e) >> 10), offset, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,48 @@
+package rds
+
+import "testing"
+
+// loadBlock shifts a 26-bit block into a fresh block synchronizer.
+func loadBlock(s *blockSync, block uint32) {
+	for i := blockLength - 1; i >= 0; i-- {
+		s.reg = (s.reg<<1 | block>>i&1) & (1<<blockLength - 1)
+	}
+}
+
+func TestSyndrome_ValidBlocks(t *testing.T) {
+	for offset := range numOffsets {
+		for _, data := range []uint16{0x0000, 0x1234, 0xC201, 0xFFFF} {
+			block := uint32(data)<<10 | uint32(checkword(data)^offsetWords[offset])
+			if s := syndrome(block, offset); s != 0 {
+				t.Errorf("Offset %d, data %04X: expected zero syndrome, got %03X", offset, data, s)
+			}
+			for other := range numOffsets {
+				if other != offset && syndrome(block, other) == 0 {
+					t.Errorf("Offset %d, data %04X: block also matched offset %d", offset, data, other)
+				}
+			}
+		}
+	}
+}
+
+func TestBlockSync_BurstCorrection(t *testing.T) {
+	const data = 0xC201
+	block := uint32(data)<<10 | uint32(checkword(data)^offsetWords[offsetB])
+	s := newBlockSync()
+
+	for _, burst := range []uint32{0b1, 0b11} {
+		for shift := 0; shift <= blockLength-2; shift++ {
+			loadBlock(s, block^burst<<shift)
+			got, offset, ok := s.decode(1)
+			if !ok || got != data || offset != offsetB {
+				t.Fatalf("Burst %b at bit %d: expected %04X, got %04X (ok=%v)", burst, shift, data, got, ok)
+			}
+		}
+	}
+
+	// A burst longer than we correct must not be accepted as valid data.
+	loadBlock(s, block^0b10101<<4)
+	if got, _, ok := s.decode(1); ok && got == data {
+		t.Errorf("Expected a 5-bit error pattern to be rejected, got %04X", got)
+	}
+}
@@ -0,0 +1,165 @@
+package rds
+
+import (
+	"math"
+
+	"go-audio-mini-project/internal/dsp"
+)
+
+const (
+	subcarrierFrequency = 57000.0
+	bitRate             = 1187.5
+	basebandRate        = 48000
+	basebandTaps        = 63
+	rdsFilterTaps       = 121
+	rdsBandwidth        = 2400.0
+)
+
+// demodulator recovers the RDS bit stream from an FM multiplex signal. It
+// mixes the 57 kHz subcarrier down to baseband, tracks the BPSK carrier with
+// a Costas loop, recovers biphase symbol timing with an early-late gate and
+// differentially decodes the resulting symbols.
+type demodulator struct {
+	// 57 kHz mixdown.
+	ncoPhase float64
+	ncoStep  float64
+
+	// Decimation to the baseband rate, then the RDS channel filter.
+	decimI   *dsp.RationalResampler
+	decimQ   *dsp.RationalResampler
+	channelI *dsp.FIRFilter
+	channelQ *dsp.FIRFilter
+
+	// Costas loop for carrier phase.
+	carrierPhase float64
+	carrierFreq  float64
+	alpha        float64
+	beta         float64
+	power        float64
+
+	// Biphase matched filter, computed with running sums over two half-bit
+	// windows of the carrier-corrected signal.
+	half     int
+	history  []float64
+	histPos  int
+	sumNewer float64
+	sumOlder float64
+
+	// Symbol timing recovery on the matched filter output. The biphase
+	// matched filter also peaks half way through a bit whenever two
+	// consecutive symbols are equal, so the average output at both points is
+	// compared to keep the sampling instant on the bit boundary.
+	samplesPerBit float64
+	gate          int
+	matched       []float64
+	sample        int64
+	nextBit       float64
+	onTime        float64
+	halfway       float64
+
+	prevSymbol bool
+}
+
+func newDemodulator(sampleRate int) *demodulator {
+	decimation := max(sampleRate/basebandRate, 1)
+	rate := float64(sampleRate) / float64(decimation)
+	channelTaps := dsp.DesignFIRLowPass(rdsFilterTaps, rdsBandwidth/rate)
+
+	samplesPerBit := rate / bitRate
+	half := int(math.Round(samplesPerBit / 2))
+	wn := 2 * math.Pi * 10 / rate
+	d := &demodulator{
+		ncoStep:       2 * math.Pi * subcarrierFrequency / float64(sampleRate),
+		decimI:        dsp.NewRationalResampler(1, decimation, basebandTaps, 0.4/float64(decimation)),
+		decimQ:        dsp.NewRationalResampler(1, decimation, basebandTaps, 0.4/float64(decimation)),
+		channelI:      dsp.NewFIRFilter(channelTaps),
+		channelQ:      dsp.NewFIRFilter(channelTaps),
+		alpha:         2 * 0.707 * wn,
+		beta:          wn * wn,
+		half:          half,
+		history:       make([]float64, 2*half),
+		samplesPerBit: samplesPerBit,
+		gate:          max(int(math.Round(samplesPerBit/8)), 1),
+		nextBit:       samplesPerBit,
+	}
+	d.matched = make([]float64, half+4*d.gate)
+	return d
+}
+
+// process demodulates a block of MPX samples and appends the decoded bits to
+// bits, returning the extended slice.
+func (d *demodulator) process(mpx []float32, bits []byte) []byte {
+	I := make([]float32, len(mpx))
+	Q := make([]float32, len(mpx))
+	for i, x := range mpx {
+		s, c := math.Sincos(d.ncoPhase)
+		I[i] = x * float32(c)
+		Q[i] = -x * float32(s)
+		d.ncoPhase = math.Mod(d.ncoPhase+d.ncoStep, 2*math.Pi)
+	}
+
+	I = d.channelI.Process(d.decimI.Process(I))
+	Q = d.channelQ.Process(d.decimQ.Process(Q))
+
+	for i := range I {
+		// Remove the residual carrier phase, then update the Costas loop.
+		s, c := math.Sincos(d.carrierPhase)
+		re := float64(I[i])*c + float64(Q[i])*s
+		im := float64(Q[i])*c - float64(I[i])*s
+		d.power += 0.001 * (re*re + im*im - d.power)
+		e := re * im / (d.power + 1e-20)
+		d.carrierFreq += d.beta * e
+		d.carrierPhase = math.Mod(d.carrierPhase+d.carrierFreq+d.alpha*e, 2*math.Pi)
+
+		if bit, ok := d.symbol(re); ok {
+			bits = append(bits, bit)
+		}
+	}
+	return bits
+}
+
+// symbol feeds one carrier-corrected sample through the biphase matched
+// filter and timing recovery. It returns a differentially decoded bit when a
+// symbol decision was made.
+func (d *demodulator) symbol(x float64) (byte, bool) {
+	n := len(d.history)
+	mid := d.history[(d.histPos+d.half)%n]
+	oldest := d.history[d.histPos]
+	d.sumNewer += x - mid
+	d.sumOlder += mid - oldest
+	d.history[d.histPos] = x
+	d.histPos = (d.histPos + 1) % n
+
+	d.matched[d.sample%int64(len(d.matched))] = d.sumNewer - d.sumOlder
+	d.sample++
+
+	// Decide once the late gate sample for the current bit is available.
+	center := int64(math.Round(d.nextBit))
+	if d.sample <= center+int64(d.gate) {
+		return 0, false
+	}
+	at := func(i int64) float64 { return d.matched[i%int64(len(d.matched))] }
+	on := at(center)
+	early := math.Abs(at(center - int64(d.gate)))
+	late := math.Abs(at(center + int64(d.gate)))
+
+	// Move towards the larger of the early and late gate outputs.
+	e := (late - early) / (late + early + 1e-20)
+	d.nextBit += d.samplesPerBit + 0.2*e*float64(d.gate)
+
+	// Slip by half a bit if we have locked to the middle of the bits.
+	d.onTime += 0.05 * (math.Abs(on) - d.onTime)
+	d.halfway += 0.05 * (math.Abs(at(center-int64(d.half))) - d.halfway)
+	if d.halfway > 1.5*d.onTime {
+		d.nextBit += d.samplesPerBit / 2
+		d.onTime, d.halfway = d.halfway, d.onTime
+	}
+
+	symbol := on > 0
+	bit := byte(0)
+	if symbol != d.prevSymbol {
+		bit = 1
+	}
+	d.prevSymbol = symbol
+	return bit, true
+}
@@ -0,0 +1,271 @@
+package rds
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Alternative frequency codes, method A.
+const (
+	afFirstFrequency = 1
+	afLastFrequency  = 204
+	afFiller         = 205
+	afLFMFFollows    = 250
+)
+
+// mjdEpoch is day zero of the Modified Julian Date used by clock-time groups.
+var mjdEpoch = time.Date(1858, time.November, 17, 0, 0, 0, 0, time.UTC)
+
+// parser interprets RDS groups and keeps track of the station information
+// they carry, reporting each field as it changes.
+type parser struct {
+	rbds bool
+
+	pi          uint16
+	piCandidate uint16
+	piValid     bool
+
+	pty      PTY
+	ptyValid bool
+
+	ps       [8]byte
+	psSeen   uint8
+	lastPS   string
+	rt       [64]byte
+	rtSeen   uint16
+	rtEnd    int
+	rtFlag   int
+	lastRT   string
+	afs      []float64
+	skipNext bool
+}
+
+func newParser(rbds bool) *parser {
+	p := &parser{rbds: rbds, rtFlag: -1}
+	p.clearRadioText()
+	return p
+}
+
+// parse extracts station information from a group and returns the fields
+// that changed.
+func (p *parser) parse(g group) []Update {
+	var updates []Update
+
+	// The PI code is repeated in block C' of version B groups.
+	switch {
+	case g.valid[0]:
+		updates = p.updatePI(g.blocks[0], updates)
+	case g.valid[2] && g.versionB:
+		updates = p.updatePI(g.blocks[2], updates)
+	}
+
+	if !g.valid[1] {
+		return updates
+	}
+	b := g.blocks[1]
+	groupType := b >> 12
+	versionB := b&0x0800 != 0
+
+	if pty := PTY(b >> 5 & 0x1F); !p.ptyValid || pty != p.pty {
+		p.pty, p.ptyValid = pty, true
+		updates = append(updates, PTYUpdate{PTY: pty, RBDS: p.rbds})
+	}
+
+	switch {
+	case groupType == 0:
+		updates = p.parseServiceName(g, versionB, updates)
+	case groupType == 2:
+		updates = p.parseRadioText(g, versionB, updates)
+	case groupType == 4 && !versionB:
+		updates = p.parseClockTime(g, updates)
+	}
+	return updates
+}
+
+// updatePI accepts a new PI code once it has been received twice in a row,
+// which guards against corrupted blocks that happen to pass the CRC.
+func (p *parser) updatePI(pi uint16, updates []Update) []Update {
+	if pi != p.piCandidate {
+		p.piCandidate = pi
+		return updates
+	}
+	if p.piValid && pi == p.pi {
+		return updates
+	}
+	if p.piValid {
+		// A different station: forget what the last one sent.
+		p.clearStation()
+	}
+	p.pi, p.piValid = pi, true
+	return append(updates, PIUpdate{PI: pi})
+}
+
+// clearStation forgets the programme type, Programme Service name,
+// RadioText and alternative frequencies, so a newly tuned station's are
+// reported afresh rather than mixed with the previous one's.
+func (p *parser) clearStation() {
+	p.ptyValid = false
+	p.ps = [8]byte{}
+	p.psSeen = 0
+	p.lastPS = ""
+	p.rtFlag = -1
+	p.lastRT = ""
+	p.clearRadioText()
+	p.afs = nil
+	p.skipNext = false
+}
+
+// parseServiceName handles group 0A/0B, carrying two characters of the
+// Programme Service name and, in version A, alternative frequencies.
+func (p *parser) parseServiceName(g group, versionB bool, updates []Update) []Update {
+	if !versionB && g.valid[2] {
+		updates = p.parseAltFrequencies(g.blocks[2], updates)
+	}
+	if !g.valid[3] {
+		return updates
+	}
+
+	segment := g.blocks[1] & 0x3
+	p.ps[segment*2] = byte(g.blocks[3] >> 8)
+	p.ps[segment*2+1] = byte(g.blocks[3])
+	p.psSeen |= 1 << segment
+
+	if p.psSeen == 0xF {
+		p.psSeen = 0
+		if name := decodeText(p.ps[:]); name != p.lastPS {
+			p.lastPS = name
+			updates = append(updates, PSUpdate{Name: name})
+		}
+	}
+	return updates
+}
+
+// parseAltFrequencies handles the two method A alternative frequency codes
+// carried in block C of group 0A.
+func (p *parser) parseAltFrequencies(c uint16, updates []Update) []Update {
+	changed := false
+	for _, code := range []uint8{uint8(c >> 8), uint8(c)} {
+		if p.skipNext {
+			// The previous code announced an LF/MF frequency.
+			p.skipNext = false
+			continue
+		}
+		switch {
+		case code == afFiller:
+			// Pads out a pair with a single frequency.
+		case code == afLFMFFollows:
+			p.skipNext = true
+		case code >= afFirstFrequency && code <= afLastFrequency:
+			freq := float64(875+int(code)) / 10
+			if !slices.Contains(p.afs, freq) {
+				p.afs = append(p.afs, freq)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return updates
+	}
+	slices.Sort(p.afs)
+	return append(updates, AltFrequenciesUpdate{Frequencies: slices.Clone(p.afs)})
+}
+
+// parseRadioText handles group 2A, carrying four characters of a 64 character
+// message, and group 2B, carrying two characters of a 32 character message.
+func (p *parser) parseRadioText(g group, versionB bool, updates []Update) []Update {
+	b := g.blocks[1]
+	if flag := int(b >> 4 & 1); flag != p.rtFlag {
+		// A change of the text A/B flag means a new message follows.
+		p.rtFlag = flag
+		p.clearRadioText()
+	}
+
+	segment := int(b & 0xF)
+	var chars []byte
+	if versionB {
+		if !g.valid[3] {
+			return updates
+		}
+		chars = []byte{byte(g.blocks[3] >> 8), byte(g.blocks[3])}
+	} else {
+		if !g.valid[2] || !g.valid[3] {
+			return updates
+		}
+		chars = []byte{byte(g.blocks[2] >> 8), byte(g.blocks[2]), byte(g.blocks[3] >> 8), byte(g.blocks[3])}
+	}
+
+	for i, ch := range chars {
+		pos := segment*len(chars) + i
+		if ch == '\r' {
+			p.rtEnd = pos
+			break
+		}
+		p.rt[pos] = ch
+	}
+	p.rtSeen |= 1 << segment
+
+	// The message is complete once every segment up to the end has arrived.
+	length := len(p.rt)
+	if versionB {
+		length = 32
+	}
+	if p.rtEnd >= 0 {
+		length = p.rtEnd
+	}
+	segments := (length + len(chars) - 1) / len(chars)
+	if mask := uint16(1<<segments - 1); p.rtSeen&mask != mask {
+		return updates
+	}
+
+	text := decodeText(p.rt[:length])
+	if text != p.lastRT {
+		p.lastRT = text
+		updates = append(updates, RadioTextUpdate{Text: text})
+	}
+	return updates
+}
+
+func (p *parser) clearRadioText() {
+	for i := range p.rt {
+		p.rt[i] = ' '
+	}
+	p.rtSeen = 0
+	p.rtEnd = -1
+}
+
+// parseClockTime handles group 4A, carrying the date, UTC time and local
+// time offset.
+func (p *parser) parseClockTime(g group, updates []Update) []Update {
+	if !g.valid[2] || !g.valid[3] {
+		return updates
+	}
+	b, c, d := uint32(g.blocks[1]), uint32(g.blocks[2]), uint32(g.blocks[3])
+
+	mjd := (b&0x3)<<15 | c>>1
+	hour := (c&1)<<4 | d>>12
+	minute := d >> 6 & 0x3F
+	if hour > 23 || minute > 59 {
+		return updates
+	}
+	offset := int(d&0x1F) * 30 * 60
+	if d&0x20 != 0 {
+		offset = -offset
+	}
+
+	utc := mjdEpoch.AddDate(0, 0, int(mjd)).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	return append(updates, ClockTimeUpdate{Time: utc.In(time.FixedZone("", offset))})
+}
+
+// decodeText converts RDS characters to a string, replacing characters outside
+// the printable ASCII range with '?' and trimming trailing padding.
+func decodeText(chars []byte) string {
+	var sb strings.Builder
+	for _, ch := range chars {
+		if ch < 0x20 || ch > 0x7E {
+			ch = '?'
+		}
+		sb.WriteByte(ch)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
@@ -0,0 +1,38 @@
+package rds
+
+// PTY is the programme type code broadcast in every RDS group.
+type PTY uint8
+
+var rdsPTYNames = [32]string{
+	"None", "News", "Current Affairs", "Information", "Sport", "Education",
+	"Drama", "Culture", "Science", "Varied", "Pop Music", "Rock Music",
+	"Easy Listening", "Light Classical", "Serious Classical", "Other Music",
+	"Weather", "Finance", "Children's Programmes", "Social Affairs", "Religion",
+	"Phone-in", "Travel", "Leisure", "Jazz Music", "Country Music",
+	"National Music", "Oldies Music", "Folk Music", "Documentary", "Alarm Test",
+	"Alarm",
+}
+
+var rbdsPTYNames = [32]string{
+	"None", "News", "Information", "Sports", "Talk", "Rock", "Classic Rock",
+	"Adult Hits", "Soft Rock", "Top 40", "Country", "Oldies", "Soft",
+	"Nostalgia", "Jazz", "Classical", "Rhythm and Blues",
+	"Soft Rhythm and Blues", "Language", "Religious Music", "Religious Talk",
+	"Personality", "Public", "College", "Spanish Talk", "Spanish Music",
+	"Hip Hop", "Unassigned", "Unassigned", "Weather", "Emergency Test",
+	"Emergency",
+}
+
+// Name returns the programme type name, using the North American RBDS table
+// when rbds is set and the European RDS table otherwise.
+func (p PTY) Name(rbds bool) string {
+	if rbds {
+		return rbdsPTYNames[p&31]
+	}
+	return rdsPTYNames[p&31]
+}
+
+// String returns the RDS programme type name.
+func (p PTY) String() string {
+	return p.Name(false)
+}
@@ -0,0 +1,114 @@
+// Package rds decodes the Radio Data System (RDS, and its North American
+// variant RBDS) carried on the 57 kHz subcarrier of an FM broadcast multiplex.
+package rds
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Update is a change in the decoded station information. It is one of
+// PIUpdate, PTYUpdate, PSUpdate, RadioTextUpdate, ClockTimeUpdate or
+// AltFrequenciesUpdate.
+type Update interface {
+	fmt.Stringer
+	update()
+}
+
+// PIUpdate reports a new Programme Identification code.
+type PIUpdate struct {
+	PI uint16
+}
+
+// PTYUpdate reports a new programme type.
+type PTYUpdate struct {
+	PTY  PTY
+	RBDS bool
+}
+
+// PSUpdate reports a new Programme Service name.
+type PSUpdate struct {
+	Name string
+}
+
+// RadioTextUpdate reports a new RadioText message.
+type RadioTextUpdate struct {
+	Text string
+}
+
+// ClockTimeUpdate reports the clock time broadcast by the station, in the
+// station's local time zone.
+type ClockTimeUpdate struct {
+	Time time.Time
+}
+
+// AltFrequenciesUpdate reports the list of alternative frequencies, in MHz.
+type AltFrequenciesUpdate struct {
+	Frequencies []float64
+}
+
+func (PIUpdate) update()             {}
+func (PTYUpdate) update()            {}
+func (PSUpdate) update()             {}
+func (RadioTextUpdate) update()      {}
+func (ClockTimeUpdate) update()      {}
+func (AltFrequenciesUpdate) update() {}
+
+func (u PIUpdate) String() string  { return fmt.Sprintf("PI: %04X", u.PI) }
+func (u PTYUpdate) String() string { return fmt.Sprintf("PTY: %d (%s)", u.PTY, u.PTY.Name(u.RBDS)) }
+func (u PSUpdate) String() string  { return fmt.Sprintf("PS: %q", u.Name) }
+
+func (u RadioTextUpdate) String() string {
+	return fmt.Sprintf("RadioText: %q", u.Text)
+}
+
+func (u ClockTimeUpdate) String() string {
+	return "Clock time: " + u.Time.Format("2006-01-02 15:04 -07:00")
+}
+
+func (u AltFrequenciesUpdate) String() string {
+	freqs := make([]string, len(u.Frequencies))
+	for i, f := range u.Frequencies {
+		freqs[i] = fmt.Sprintf("%.1f", f)
+	}
+	return "Alternative frequencies: " + strings.Join(freqs, ", ") + " MHz"
+}
+
+// Decoder turns an FM multiplex signal into a stream of RDS updates.
+type Decoder struct {
+	demod  *demodulator
+	sync   *blockSync
+	parser *parser
+	bits   []byte
+}
+
+// NewDecoder creates an RDS decoder for an MPX signal sampled at sampleRate,
+// such as the output of dsp.Demodulator. When rbds is set, programme types are
+// named using the North American RBDS table.
+func NewDecoder(sampleRate int, rbds bool) *Decoder {
+	return &Decoder{
+		demod:  newDemodulator(sampleRate),
+		sync:   newBlockSync(),
+		parser: newParser(rbds),
+	}
+}
+
+// Synced reports whether the decoder is currently aligned to RDS blocks.
+func (d *Decoder) Synced() bool {
+	return d.sync.Synced()
+}
+
+// Process decodes a block of MPX samples and returns any station information
+// that changed as a result.
+func (d *Decoder) Process(mpx []float32) []Update {
+	d.bits = d.demod.process(mpx, d.bits[:0])
+
+	var updates []Update
+	for _, bit := range d.bits {
+		if g, ok := d.sync.push(bit); ok {
+			updates = append(updates, d.parser.parse(g)...)
+		}
+	}
+	return updates
+}
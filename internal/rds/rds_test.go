@@ -0,0 +1,175 @@
+package rds
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// encodeBlock appends the 26-bit block for data with the given offset.
+func encodeBlock(bits []byte, data uint16, offset int) []byte {
+	block := uint32(data)<<10 | uint32(checkword(data)^offsetWords[offset])
+	for i := blockLength - 1; i >= 0; i-- {
+		bits = append(bits, byte(block>>i&1))
+	}
+	return bits
+}
+
+// encodeGroup appends the four blocks of a group to bits.
+func encodeGroup(bits []byte, blocks [4]uint16) []byte {
+	third := offsetC
+	if blocks[1]&0x0800 != 0 {
+		third = offsetCPrime
+	}
+	bits = encodeBlock(bits, blocks[0], offsetA)
+	bits = encodeBlock(bits, blocks[1], offsetB)
+	bits = encodeBlock(bits, blocks[2], third)
+	return encodeBlock(bits, blocks[3], offsetD)
+}
+
+// modulateRDS builds an MPX signal at sampleRate carrying bits as a
+// differentially encoded, biphase modulated 57 kHz subcarrier, mixed with a
+// pilot and an audio tone.
+func modulateRDS(sampleRate int, bits []byte) []float32 {
+	numSamples := int(float64(len(bits)) / bitRate * float64(sampleRate))
+	mpx := make([]float32, numSamples)
+	var symbol byte
+	lastBit := -1
+	for i := range mpx {
+		t := float64(i) / float64(sampleRate)
+		pos := t * bitRate
+		bit := int(pos)
+		if bit != lastBit {
+			symbol ^= bits[bit]
+			lastBit = bit
+		}
+		level := 1.0
+		if symbol == 0 {
+			level = -1
+		}
+		if pos-float64(bit) >= 0.5 {
+			level = -level
+		}
+		x := 0.04 * level * math.Sin(2*math.Pi*subcarrierFrequency*t)
+		x += 0.1 * math.Sin(2*math.Pi*19000*t)
+		x += 0.5 * math.Sin(2*math.Pi*1000*t)
+		mpx[i] = float32(x)
+	}
+	return mpx
+}
+
+func TestDecoder_EndToEnd(t *testing.T) {
+	const sampleRate = 240000
+	const pi = 0xC201
+	const pty = 10
+	const ps = "TESTFM  "
+	const radioText = "Hello from the RDS test"
+
+	var bits []byte
+	for repeat := 0; repeat < 3; repeat++ {
+		for seg := uint16(0); seg < 4; seg++ {
+			// Group 0A with two alternative frequencies in block C.
+			b := uint16(0)<<12 | pty<<5 | seg
+			c := uint16(1)<<8 | 6 // 87.6 MHz, 88.1 MHz
+			d := uint16(ps[seg*2])<<8 | uint16(ps[seg*2+1])
+			bits = encodeGroup(bits, [4]uint16{pi, b, c, d})
+		}
+		text := []byte(radioText + "\r   ")
+		for seg := uint16(0); int(seg)*4 < len(radioText)+1; seg++ {
+			b := uint16(2)<<12 | pty<<5 | seg
+			chars := text[seg*4 : seg*4+4]
+			c := uint16(chars[0])<<8 | uint16(chars[1])
+			d := uint16(chars[2])<<8 | uint16(chars[3])
+			bits = encodeGroup(bits, [4]uint16{pi, b, c, d})
+		}
+	}
+
+	dec := NewDecoder(sampleRate, false)
+	mpx := modulateRDS(sampleRate, bits)
+	var updates []Update
+	const blockSize = 8192
+	for i := 0; i < len(mpx); i += blockSize {
+		updates = append(updates, dec.Process(mpx[i:min(i+blockSize, len(mpx))])...)
+	}
+
+	want := []Update{
+		PIUpdate{PI: pi},
+		PTYUpdate{PTY: pty},
+		PSUpdate{Name: "TESTFM"},
+		RadioTextUpdate{Text: radioText},
+	}
+	for _, w := range want {
+		if !slices.ContainsFunc(updates, func(u Update) bool { return u == w }) {
+			t.Errorf("Missing update %v, got %v", w, updates)
+		}
+	}
+
+	var afs []float64
+	for _, u := range updates {
+		if af, ok := u.(AltFrequenciesUpdate); ok {
+			afs = af.Frequencies
+		}
+	}
+	if !slices.Equal(afs, []float64{87.6, 88.1}) {
+		t.Errorf("Expected alternative frequencies [87.6 88.1], got %v", afs)
+	}
+}
+
+func TestParser_ClockTime(t *testing.T) {
+	// 2023-02-25 (MJD 60000) 13:45 UTC, local offset +01:00.
+	const mjd = 60000
+	b := uint16(4)<<12 | uint16(mjd>>15)
+	c := uint16(mjd<<1&0xFFFE) | 13>>4
+	d := uint16(13&0xF)<<12 | 45<<6 | 2
+
+	p := newParser(false)
+	updates := p.parse(group{blocks: [4]uint16{0x1234, b, c, d}, valid: [4]bool{true, true, true, true}})
+
+	var got time.Time
+	for _, u := range updates {
+		if ct, ok := u.(ClockTimeUpdate); ok {
+			got = ct.Time
+		}
+	}
+	want := time.Date(2023, time.February, 25, 13, 45, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("Expected clock time %v, got %v", want, got)
+	}
+	if _, offset := got.Zone(); offset != 3600 {
+		t.Errorf("Expected local offset of 3600s, got %d", offset)
+	}
+}
+
+func TestParser_NewStationClearsInformation(t *testing.T) {
+	p := newParser(false)
+	parse := func(blocks [4]uint16) []Update {
+		return p.parse(group{blocks: blocks, valid: [4]bool{true, true, true, true}})
+	}
+
+	// Station 1234 sends the first segment of its name and an alternative
+	// frequency of 87.6 MHz, padded with the filler code.
+	for range 2 {
+		parse([4]uint16{0x1234, 0x0000, 1<<8 | afFiller, 'A'<<8 | 'B'})
+	}
+	if len(p.afs) != 1 || p.afs[0] != 87.6 {
+		t.Fatalf("Expected alternative frequencies [87.6], got %v", p.afs)
+	}
+
+	// Retuning to station 5678 forgets them. Its first group is not yet
+	// trusted, so the second one switches.
+	parse([4]uint16{0x5678, 0x0000, 6<<8 | afFiller, 'C'<<8 | 'D'})
+	updates := parse([4]uint16{0x5678, 0x0000, 6<<8 | afFiller, 'C'<<8 | 'D'})
+	var afs []float64
+	for _, u := range updates {
+		if af, ok := u.(AltFrequenciesUpdate); ok {
+			afs = af.Frequencies
+		}
+	}
+	if len(afs) != 1 || afs[0] != 88.1 {
+		t.Errorf("Expected only the new station's frequency [88.1], got %v", afs)
+	}
+	if p.ps[0] != 'C' || p.ps[2] != 0 {
+		t.Errorf("Expected the old station's name to be cleared, got %q", p.ps)
+	}
+}
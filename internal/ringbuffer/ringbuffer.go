@@ -1,30 +1,105 @@
 package ringbuffer
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
 
-// RingBuffer is a concurrent-safe ring buffer for int16 samples.
-type RingBuffer struct {
-	buf        []int16
+// ErrClosed is returned by Write after the buffer has been closed.
+var ErrClosed = errors.New("write to closed ring buffer")
+
+// ErrWouldBlock is returned by TryRead and TryWrite when the operation would
+// have had to wait.
+var ErrWouldBlock = errors.New("ring buffer operation would block")
+
+// OverflowPolicy decides what Write does when the buffer is full.
+type OverflowPolicy int
+
+const (
+	// Block waits for the reader to make space, so no data is lost. It suits
+	// files, where the reader can simply wait.
+	Block OverflowPolicy = iota
+	// DropNewest discards the values that don't fit, keeping the buffered
+	// data intact.
+	DropNewest
+	// OverwriteOldest discards the oldest buffered values to make space, so
+	// the reader skips ahead to the most recent data.
+	OverwriteOldest
+)
+
+var policyNames = map[OverflowPolicy]string{
+	Block:           "block",
+	DropNewest:      "drop-newest",
+	OverwriteOldest: "overwrite-oldest",
+}
+
+// String returns the policy name, such as "drop-newest".
+func (p OverflowPolicy) String() string {
+	if name, ok := policyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// ParseOverflowPolicy returns the policy with the given name.
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for p, n := range policyNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q (want block, drop-newest or overwrite-oldest)", name)
+}
+
+// Stats counts what has happened to a buffer since it was created.
+type Stats struct {
+	Written   int64 // Values accepted by Write.
+	Read      int64 // Values returned by Read.
+	Dropped   int64 // Values lost to the overflow policy.
+	Overruns  int64 // Writes that found the buffer full.
+	Underruns int64 // Reads that found too little data buffered.
+	HighWater int   // Most values ever buffered at once.
+}
+
+// RingBuffer is a concurrent-safe ring buffer of values of type T, such as
+// complex64 IQ samples, float32 audio samples or bytes.
+type RingBuffer[T any] struct {
+	buf        []T
 	size       int
 	readIndex  int
 	writeIndex int
 	closed     bool
+	err        error // Why the writer closed the buffer, if not at the end of the stream.
+	policy     OverflowPolicy
+	stats      Stats
 	mu         sync.Mutex
 	cond       *sync.Cond
 }
 
-// New creates a new RingBuffer of a given size.
-func New(size int) *RingBuffer {
-	rb := &RingBuffer{
-		buf:  make([]int16, size),
-		size: size,
+// New creates a new RingBuffer of a given size whose Write blocks when it is
+// full.
+func New[T any](size int) *RingBuffer[T] {
+	return NewWithPolicy[T](size, Block)
+}
+
+// NewWithPolicy creates a new RingBuffer of a given size that handles being
+// full according to policy.
+func NewWithPolicy[T any](size int, policy OverflowPolicy) *RingBuffer[T] {
+	rb := &RingBuffer[T]{
+		buf:    make([]T, size),
+		size:   size,
+		policy: policy,
 	}
 	rb.cond = sync.NewCond(&rb.mu)
 	return rb
 }
 
 // AvailableWrite returns the number of samples that can be written to the buffer.
-func (rb *RingBuffer) AvailableWrite() int {
+func (rb *RingBuffer[T]) AvailableWrite() int {
 	if rb.writeIndex >= rb.readIndex {
 		return rb.size - (rb.writeIndex - rb.readIndex) - 1
 	}
@@ -32,7 +107,7 @@ func (rb *RingBuffer) AvailableWrite() int {
 }
 
 // AvailableRead returns the number of samples available for reading.
-func (rb *RingBuffer) AvailableRead() int {
+func (rb *RingBuffer[T]) AvailableRead() int {
 	if rb.writeIndex >= rb.readIndex {
 		return rb.writeIndex - rb.readIndex
 	}
@@ -41,63 +116,189 @@ func (rb *RingBuffer) AvailableRead() int {
 
 // Close marks the buffer as closed, indicating no more writes will occur.
 // It broadcasts to all waiting readers to wake them up.
-func (rb *RingBuffer) Close() {
+func (rb *RingBuffer[T]) Close() {
+	rb.CloseWithError(nil)
+}
+
+// CloseWithError closes the buffer like Close, recording err as the reason
+// the writer stopped. Readers still receive the buffered data, and can then
+// retrieve err with Err. Only the first close has any effect.
+func (rb *RingBuffer[T]) CloseWithError(err error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
+	if rb.closed {
+		return
+	}
 	rb.closed = true
+	rb.err = err
 	rb.cond.Broadcast() // Wake up any readers waiting for data.
 }
 
-// Write adds data to the buffer, blocking until space is available.
-func (rb *RingBuffer) Write(data []int16) {
+// Err returns the error the buffer was closed with, or nil if it is still
+// open or was closed normally.
+func (rb *RingBuffer[T]) Err() error {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.err
+}
+
+// Stats returns the buffer's counters.
+func (rb *RingBuffer[T]) Stats() Stats {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	return rb.stats
+}
+
+// Write adds data to the buffer. When there isn't space for it all, it
+// blocks, drops or overwrites according to the buffer's overflow policy. It
+// returns ErrClosed if the buffer has been closed.
+func (rb *RingBuffer[T]) Write(data []T) error {
+	_, err := rb.write(context.Background(), data, true)
+	return err
+}
+
+// WriteContext is like Write, but stops waiting for space when ctx is done
+// and returns ctx.Err(). It returns the number of values written, which
+// includes any written before ctx was done.
+func (rb *RingBuffer[T]) WriteContext(ctx context.Context, data []T) (int, error) {
+	return rb.write(ctx, data, true)
+}
+
+// WriteDeadline is like WriteContext, giving up at deadline with
+// context.DeadlineExceeded.
+func (rb *RingBuffer[T]) WriteDeadline(deadline time.Time, data []T) (int, error) {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	return rb.write(ctx, data, true)
+}
+
+// TryWrite is like Write, but returns ErrWouldBlock without writing anything
+// when a Block policy buffer hasn't space for all of data.
+func (rb *RingBuffer[T]) TryWrite(data []T) error {
+	_, err := rb.write(context.Background(), data, false)
+	return err
+}
+
+// write implements the Write variants. When wait is false, it fails rather
+// than waiting for space.
+func (rb *RingBuffer[T]) write(ctx context.Context, data []T, wait bool) (int, error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
 	if rb.closed {
-		// Or return an error, but for this use case, panicking is acceptable
-		// as it indicates a programming error.
-		panic("write to closed ring buffer")
+		return 0, ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if excess := len(data) - rb.AvailableWrite(); excess > 0 {
+		if !wait && rb.policy == Block {
+			return 0, ErrWouldBlock
+		}
+		rb.stats.Overruns++
+		switch rb.policy {
+		case DropNewest:
+			rb.stats.Dropped += int64(excess)
+			data = data[:len(data)-excess]
+		case OverwriteOldest:
+			// Data longer than the buffer only leaves its newest values.
+			if skip := len(data) - (rb.size - 1); skip > 0 {
+				rb.stats.Dropped += int64(skip)
+				data = data[skip:]
+				excess -= skip
+			}
+			rb.stats.Dropped += int64(excess)
+			rb.readIndex = (rb.readIndex + excess) % rb.size
+		}
 	}
 
 	n := len(data)
+	var stop func() bool
 	for i := 0; i < n; {
 		// Wait for space to become available.
 		for rb.AvailableWrite() == 0 {
+			if stop == nil {
+				stop = rb.wakeOnDone(ctx)
+				defer stop()
+			}
 			rb.cond.Wait()
+			if rb.closed {
+				return i, ErrClosed
+			}
+			if err := ctx.Err(); err != nil {
+				return i, err
+			}
 		}
 
-		// Copy in one or two chunks.
-		if rb.writeIndex >= rb.readIndex {
-			// Write up to the end of the buffer.
-			written := copy(rb.buf[rb.writeIndex:], data[i:])
-			rb.writeIndex = (rb.writeIndex + written) % rb.size
-			i += written
-		} else {
-			// Write up to the read index.
-			written := copy(rb.buf[rb.writeIndex:rb.readIndex-1], data[i:])
-			rb.writeIndex += written
-			i += written
-		}
+		// Copy up to the end of the buffer or the free space, whichever
+		// comes first; the rest wraps around on the next iteration.
+		end := min(rb.size, rb.writeIndex+rb.AvailableWrite())
+		written := copy(rb.buf[rb.writeIndex:end], data[i:])
+		rb.writeIndex = (rb.writeIndex + written) % rb.size
+		i += written
+		rb.stats.Written += int64(written)
+		rb.stats.HighWater = max(rb.stats.HighWater, rb.AvailableRead())
 		rb.cond.Broadcast() // Signal reader that data is available.
 	}
+	return n, nil
 }
 
-// Read retrieves n samples from the buffer, blocking until they are available.
+// Read retrieves n values from the buffer, blocking until they are available.
 // If the buffer is closed and no more data is available, it returns nil.
-func (rb *RingBuffer) Read(n int) []int16 {
+func (rb *RingBuffer[T]) Read(n int) []T {
+	data, _ := rb.read(context.Background(), n, true)
+	return data
+}
+
+// ReadContext is like Read, but stops waiting when ctx is done and returns
+// ctx.Err() without consuming anything. At the end of the stream it returns
+// io.EOF.
+func (rb *RingBuffer[T]) ReadContext(ctx context.Context, n int) ([]T, error) {
+	return rb.read(ctx, n, true)
+}
+
+// ReadDeadline is like ReadContext, giving up at deadline with
+// context.DeadlineExceeded.
+func (rb *RingBuffer[T]) ReadDeadline(deadline time.Time, n int) ([]T, error) {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	return rb.read(ctx, n, true)
+}
+
+// TryRead is like ReadContext, but returns ErrWouldBlock instead of waiting
+// when fewer than n values are buffered.
+func (rb *RingBuffer[T]) TryRead(n int) ([]T, error) {
+	return rb.read(context.Background(), n, false)
+}
+
+// read implements the Read variants. When wait is false, it fails rather than
+// waiting for data.
+func (rb *RingBuffer[T]) read(ctx context.Context, n int, wait bool) ([]T, error) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
 	// Wait for data, but stop waiting if the buffer is closed.
 	// The reader should wait as long as the buffer doesn't have enough data AND it's not closed.
 	// Once closed, the reader should proceed to read whatever is left.
+	if !rb.closed && rb.AvailableRead() < n {
+		rb.stats.Underruns++
+		if !wait {
+			return nil, ErrWouldBlock
+		}
+		stop := rb.wakeOnDone(ctx)
+		defer stop()
+	}
 	for !rb.closed && rb.AvailableRead() < n {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		rb.cond.Wait()
 	}
 
 	// If the buffer is closed and empty, it's the end of the stream.
 	if rb.closed && rb.AvailableRead() == 0 {
-		return nil
+		return nil, io.EOF
 	}
 
 	// Read what's available, up to a maximum of n samples.
@@ -107,10 +308,10 @@ func (rb *RingBuffer) Read(n int) []int16 {
 	}
 
 	if readSize == 0 {
-		return nil
+		return nil, nil
 	}
 
-	data := make([]int16, readSize)
+	data := make([]T, readSize)
 	if rb.readIndex+readSize <= rb.size {
 		copy(data, rb.buf[rb.readIndex:rb.readIndex+readSize])
 	} else {
@@ -119,6 +320,17 @@ func (rb *RingBuffer) Read(n int) []int16 {
 		copy(data[part1:], rb.buf[0:readSize-part1])
 	}
 	rb.readIndex = (rb.readIndex + readSize) % rb.size
+	rb.stats.Read += int64(readSize)
 	rb.cond.Broadcast()
-	return data
+	return data, nil
+}
+
+// wakeOnDone wakes the goroutines waiting on the buffer when ctx is done, so
+// a waiting call can notice. The returned function stops it.
+func (rb *RingBuffer[T]) wakeOnDone(ctx context.Context) func() bool {
+	return context.AfterFunc(ctx, func() {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+		rb.cond.Broadcast()
+	})
 }
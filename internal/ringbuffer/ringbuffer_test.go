@@ -1,8 +1,13 @@
 package ringbuffer
 
 import (
+	"context"
+	"errors"
+	"io"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestRingBuffer_ConcurrentReadWrite(t *testing.T) {
@@ -23,7 +28,7 @@ func TestRingBuffer_ConcurrentReadWrite(t *testing.T) {
 	// 	fmt.Printf = originalFmtPrintf
 	// }()
 
-	rb := New(bufferSize)
+	rb := New[int16](bufferSize)
 
 	// Generate the source data that the writer will send.
 	// Using sequential numbers makes it easy to verify correctness later.
@@ -90,3 +95,183 @@ func TestRingBuffer_ConcurrentReadWrite(t *testing.T) {
 		}
 	}
 }
+
+func TestRingBuffer_WriteFromEmptyStart(t *testing.T) {
+	// A write starting at the read index must stop one sample short of
+	// wrapping onto it, or the full buffer looks empty and the data is lost.
+	rb := New[int16](4)
+	go func() {
+		rb.Write([]int16{1, 2, 3, 4, 5, 6})
+		rb.Close()
+	}()
+
+	var got []int16
+	for chunk := rb.Read(1); chunk != nil; chunk = rb.Read(1) {
+		got = append(got, chunk...)
+	}
+	if want := []int16{1, 2, 3, 4, 5, 6}; !slices.Equal(got, want) {
+		t.Fatalf("Expected %v, but got %v", want, got)
+	}
+}
+
+func TestRingBuffer_CloseWithError(t *testing.T) {
+	rb := New[int16](16)
+	if err := rb.Write([]int16{1, 2, 3}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	cause := errors.New("connection reset")
+	rb.CloseWithError(cause)
+	rb.Close() // Later closes must not replace the error.
+
+	if err := rb.Write([]int16{4}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed writing to a closed buffer, but got %v", err)
+	}
+	if data := rb.Read(8); len(data) != 3 {
+		t.Errorf("Expected the 3 buffered samples to be readable, but got %v", data)
+	}
+	if data := rb.Read(8); data != nil {
+		t.Errorf("Expected nil at the end of the stream, but got %v", data)
+	}
+	if err := rb.Err(); err != cause {
+		t.Errorf("Expected the close error %v, but got %v", cause, err)
+	}
+}
+
+func TestRingBuffer_CloseUnblocksWriter(t *testing.T) {
+	rb := New[int16](4)
+	done := make(chan error)
+	go func() { done <- rb.Write(make([]int16, 10)) }()
+	rb.Close()
+	if err := <-done; !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected a blocked Write to return ErrClosed, but got %v", err)
+	}
+}
+
+func TestRingBuffer_Complex(t *testing.T) {
+	rb := New[complex64](8)
+	samples := []complex64{complex(0.5, -0.5), complex(-1, 1), complex(0.25, 0)}
+	for range 4 {
+		if err := rb.Write(samples); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		got := rb.Read(len(samples))
+		for i := range samples {
+			if got[i] != samples[i] {
+				t.Fatalf("Expected %v at index %d, but got %v", samples[i], i, got[i])
+			}
+		}
+	}
+}
+
+func TestRingBuffer_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []int16
+	}{
+		{DropNewest, []int16{1, 2, 3, 4, 5, 6, 7}},
+		{OverwriteOldest, []int16{4, 5, 6, 7, 8, 9, 10}},
+	}
+	for _, tt := range tests {
+		// Holds 7 values, so writing 10 overflows by 3 without blocking.
+		rb := NewWithPolicy[int16](8, tt.policy)
+		if err := rb.Write([]int16{1, 2, 3, 4, 5}); err != nil {
+			t.Fatalf("%v: Write failed: %v", tt.policy, err)
+		}
+		if err := rb.Write([]int16{6, 7, 8, 9, 10}); err != nil {
+			t.Fatalf("%v: Write failed: %v", tt.policy, err)
+		}
+		got := rb.Read(7)
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%v: expected %v, but got %v", tt.policy, tt.want, got)
+			}
+		}
+
+		stats := rb.Stats()
+		if stats.Dropped != 3 || stats.Overruns != 1 || stats.HighWater != 7 {
+			t.Errorf("%v: expected 3 dropped in 1 overrun with a high-water mark of 7, but got %+v", tt.policy, stats)
+		}
+	}
+
+	// A write longer than the whole buffer keeps only its newest values.
+	rb := NewWithPolicy[int16](4, OverwriteOldest)
+	rb.Write([]int16{1, 2, 3, 4, 5, 6})
+	if got := rb.Read(3); len(got) != 3 || got[0] != 4 || got[2] != 6 {
+		t.Errorf("Expected [4 5 6], but got %v", got)
+	}
+}
+
+func TestRingBuffer_Underruns(t *testing.T) {
+	rb := New[int16](16)
+	rb.Write([]int16{1, 2})
+	done := make(chan []int16)
+	go func() { done <- rb.Read(4) }()
+
+	// The reader counts the underrun before waiting for the rest of its data.
+	for rb.Stats().Underruns == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	rb.Close()
+	if got := <-done; len(got) != 2 {
+		t.Errorf("Expected the 2 buffered values, but got %v", got)
+	}
+	if stats := rb.Stats(); stats.Underruns != 1 || stats.Read != 2 {
+		t.Errorf("Expected 1 underrun and 2 values read, but got %+v", stats)
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for p, name := range policyNames {
+		if got, err := ParseOverflowPolicy(name); err != nil || got != p {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v; expected %v", name, got, err, p)
+		}
+	}
+	if _, err := ParseOverflowPolicy("discard"); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
+
+func TestRingBuffer_Context(t *testing.T) {
+	rb := New[int16](4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := rb.ReadContext(ctx, 2)
+		done <- err
+	}()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled ReadContext to return context.Canceled, but got %v", err)
+	}
+
+	// Only 3 of the 5 values fit before the deadline.
+	n, err := rb.WriteDeadline(time.Now().Add(10*time.Millisecond), []int16{1, 2, 3, 4, 5})
+	if n != 3 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 3 values written before the deadline, but got %d (%v)", n, err)
+	}
+
+	// The buffer is still usable afterwards.
+	if data, err := rb.ReadDeadline(time.Now().Add(time.Second), 3); len(data) != 3 || err != nil {
+		t.Errorf("Expected the 3 written values, but got %v (%v)", data, err)
+	}
+	rb.Close()
+	if _, err := rb.ReadContext(context.Background(), 1); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the stream, but got %v", err)
+	}
+}
+
+func TestRingBuffer_Try(t *testing.T) {
+	rb := New[int16](4)
+	if _, err := rb.TryRead(1); !errors.Is(err, ErrWouldBlock) {
+		t.Errorf("Expected ErrWouldBlock reading an empty buffer, but got %v", err)
+	}
+	if err := rb.TryWrite([]int16{1, 2}); err != nil {
+		t.Fatalf("TryWrite failed: %v", err)
+	}
+	if err := rb.TryWrite([]int16{3, 4}); !errors.Is(err, ErrWouldBlock) {
+		t.Errorf("Expected ErrWouldBlock writing more than fits, but got %v", err)
+	}
+	if data, err := rb.TryRead(2); len(data) != 2 || data[1] != 2 || err != nil {
+		t.Errorf("Expected [1 2], but got %v (%v)", data, err)
+	}
+}
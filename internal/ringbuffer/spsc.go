@@ -0,0 +1,159 @@
+package ringbuffer
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+// cacheLine is the padding that keeps the producer's and consumer's indices
+// on separate cache lines, so they don't slow each other down.
+const cacheLine = 64
+
+// SPSC is a lock-free ring buffer for exactly one producer goroutine and one
+// consumer goroutine. The indices are atomic counters, each written by only
+// one side, so neither Read nor Write takes a lock unless it has to wait.
+// ReadInto and WriteFrom copy directly between the caller's slices and the
+// buffer without allocating.
+type SPSC[T any] struct {
+	buf  []T
+	mask uint64
+
+	_    [cacheLine]byte
+	head atomic.Uint64 // Total values read, advanced by the consumer.
+	_    [cacheLine - 8]byte
+	tail atomic.Uint64 // Total values written, advanced by the producer.
+	_    [cacheLine - 8]byte
+
+	readable chan struct{} // Signalled after a write, to wake a waiting consumer.
+	writable chan struct{} // Signalled after a read, to wake a waiting producer.
+	done     chan struct{} // Closed by Close.
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	err       error // Why the producer closed the buffer; set before closed.
+}
+
+// NewSPSC creates an SPSC ring buffer holding at least size values. The
+// capacity is rounded up to a power of two.
+func NewSPSC[T any](size int) *SPSC[T] {
+	capacity := uint64(1) << bits.Len64(uint64(max(size, 1)-1))
+	return &SPSC[T]{
+		buf:      make([]T, capacity),
+		mask:     capacity - 1,
+		readable: make(chan struct{}, 1),
+		writable: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+// Cap returns the number of values the buffer holds.
+func (rb *SPSC[T]) Cap() int {
+	return len(rb.buf)
+}
+
+// AvailableRead returns the number of values available for reading.
+func (rb *SPSC[T]) AvailableRead() int {
+	return int(rb.tail.Load() - rb.head.Load())
+}
+
+// AvailableWrite returns the number of values that can be written.
+func (rb *SPSC[T]) AvailableWrite() int {
+	return len(rb.buf) - rb.AvailableRead()
+}
+
+// Close marks the buffer as closed, waking the consumer to read whatever is
+// left. It must only be called by the producer.
+func (rb *SPSC[T]) Close() {
+	rb.CloseWithError(nil)
+}
+
+// CloseWithError closes the buffer like Close, recording err as the reason
+// the producer stopped, for the consumer to retrieve with Err once it has
+// read the buffered data. Only the first close has any effect.
+func (rb *SPSC[T]) CloseWithError(err error) {
+	rb.closeOnce.Do(func() {
+		rb.err = err
+		rb.closed.Store(true)
+		close(rb.done)
+	})
+}
+
+// Err returns the error the buffer was closed with, or nil if it is still
+// open or was closed normally.
+func (rb *SPSC[T]) Err() error {
+	if !rb.closed.Load() {
+		return nil
+	}
+	return rb.err
+}
+
+// WriteFrom copies all of src into the buffer, blocking while it is full. It
+// returns the number of values written, which is less than len(src) only if
+// the buffer was closed, in which case the error is ErrClosed.
+func (rb *SPSC[T]) WriteFrom(src []T) (int, error) {
+	written := 0
+	for written < len(src) {
+		if rb.closed.Load() {
+			return written, ErrClosed
+		}
+		tail := rb.tail.Load()
+		free := len(rb.buf) - int(tail-rb.head.Load())
+		if free == 0 {
+			select {
+			case <-rb.writable:
+			case <-rb.done:
+			}
+			continue
+		}
+
+		// Copy up to the end of the buffer; the rest wraps around on the
+		// next iteration.
+		start := int(tail & rb.mask)
+		n := copy(rb.buf[start:min(len(rb.buf), start+free)], src[written:])
+		rb.tail.Store(tail + uint64(n))
+		written += n
+		signal(rb.readable)
+	}
+	return written, nil
+}
+
+// ReadInto fills dst from the buffer, blocking until len(dst) values are
+// available. Once the buffer is closed it returns whatever is left, and
+// io.EOF when nothing is.
+func (rb *SPSC[T]) ReadInto(dst []T) (int, error) {
+	head := rb.head.Load()
+	available := int(rb.tail.Load() - head)
+	for available < len(dst) {
+		if rb.closed.Load() {
+			// Writes made before the close are visible now, so look again.
+			available = int(rb.tail.Load() - head)
+			if available == 0 && len(dst) > 0 {
+				return 0, io.EOF
+			}
+			break
+		}
+		select {
+		case <-rb.readable:
+		case <-rb.done:
+		}
+		available = int(rb.tail.Load() - head)
+	}
+
+	n := min(available, len(dst))
+	start := int(head & rb.mask)
+	copied := copy(dst[:n], rb.buf[start:])
+	copy(dst[copied:n], rb.buf)
+	rb.head.Store(head + uint64(n))
+	signal(rb.writable)
+	return n, nil
+}
+
+// signal wakes the goroutine waiting on ch, if any, without blocking.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,120 @@
+package ringbuffer
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSPSC_ConcurrentReadWrite(t *testing.T) {
+	const totalSamples = 200000
+	const writeChunkSize = 256
+	const readChunkSize = 192 // Non-aligned with the writes and the capacity.
+
+	rb := NewSPSC[int32](1000)
+	if rb.Cap() != 1024 {
+		t.Fatalf("Expected capacity rounded up to 1024, but got %d", rb.Cap())
+	}
+
+	go func() {
+		src := make([]int32, writeChunkSize)
+		for written := 0; written < totalSamples; written += len(src) {
+			src = src[:min(writeChunkSize, totalSamples-written)]
+			for i := range src {
+				src[i] = int32(written + i)
+			}
+			if _, err := rb.WriteFrom(src); err != nil {
+				t.Errorf("WriteFrom failed: %v", err)
+				return
+			}
+		}
+		rb.Close()
+	}()
+
+	dst := make([]int32, readChunkSize)
+	next := int32(0)
+	for {
+		n, err := rb.ReadInto(dst)
+		for _, v := range dst[:n] {
+			if v != next {
+				t.Fatalf("Data corruption: expected %d, but got %d", next, v)
+			}
+			next++
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("ReadInto failed: %v", err)
+		}
+	}
+	if next != totalSamples {
+		t.Fatalf("Data loss detected: expected %d samples, but got %d", totalSamples, next)
+	}
+}
+
+func TestSPSC_CloseWithError(t *testing.T) {
+	rb := NewSPSC[complex64](16)
+	if _, err := rb.WriteFrom([]complex64{1, 2, 3}); err != nil {
+		t.Fatalf("WriteFrom failed: %v", err)
+	}
+	cause := errors.New("connection reset")
+	rb.CloseWithError(cause)
+
+	if _, err := rb.WriteFrom([]complex64{4}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed writing to a closed buffer, but got %v", err)
+	}
+	dst := make([]complex64, 8)
+	if n, err := rb.ReadInto(dst); n != 3 || err != nil {
+		t.Errorf("Expected the 3 buffered samples, but got %d (%v)", n, err)
+	}
+	if _, err := rb.ReadInto(dst); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the stream, but got %v", err)
+	}
+	if err := rb.Err(); err != cause {
+		t.Errorf("Expected the close error %v, but got %v", cause, err)
+	}
+}
+
+// The benchmarks stream one second of a 2 MHz capture per iteration through a
+// two second buffer, in the chunk and block sizes the decoder uses.
+const (
+	benchSampleRate = 2_000_000
+	benchChunkSize  = 8192
+	benchBlockSize  = 4096
+)
+
+func BenchmarkRingBuffer(b *testing.B) {
+	rb := New[complex64](2 * benchSampleRate)
+	chunk := make([]complex64, benchChunkSize)
+	go func() {
+		for written := 0; written < b.N*benchSampleRate; written += len(chunk) {
+			rb.Write(chunk)
+		}
+		rb.Close()
+	}()
+
+	b.ResetTimer()
+	for rb.Read(benchBlockSize) != nil {
+	}
+	b.ReportMetric(float64(b.N*benchSampleRate)/b.Elapsed().Seconds()/1e6, "MS/s")
+}
+
+func BenchmarkSPSC(b *testing.B) {
+	rb := NewSPSC[complex64](2 * benchSampleRate)
+	chunk := make([]complex64, benchChunkSize)
+	go func() {
+		for written := 0; written < b.N*benchSampleRate; written += len(chunk) {
+			rb.WriteFrom(chunk)
+		}
+		rb.Close()
+	}()
+
+	b.ResetTimer()
+	block := make([]complex64, benchBlockSize)
+	for {
+		if _, err := rb.ReadInto(block); err != nil {
+			break
+		}
+	}
+	b.ReportMetric(float64(b.N*benchSampleRate)/b.Elapsed().Seconds()/1e6, "MS/s")
+}
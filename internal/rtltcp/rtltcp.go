@@ -0,0 +1,166 @@
+// Package rtltcp is a client for the rtl_tcp protocol, which streams unsigned
+// 8-bit (cu8) IQ samples from a remote RTL-SDR dongle over TCP.
+package rtltcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// magic starts the dongle information header sent by the server on connect.
+const magic = "RTL0"
+
+// Commands understood by rtl_tcp. Each is sent as a command byte followed by
+// a big-endian 32-bit parameter.
+const (
+	cmdSetFrequency           = 0x01
+	cmdSetSampleRate          = 0x02
+	cmdSetGainMode            = 0x03
+	cmdSetGain                = 0x04
+	cmdSetFrequencyCorrection = 0x05
+	cmdSetAGCMode             = 0x08
+)
+
+// TunerType identifies the tuner chip of the remote dongle.
+type TunerType uint32
+
+const (
+	TunerUnknown TunerType = iota
+	TunerE4000
+	TunerFC0012
+	TunerFC0013
+	TunerFC2580
+	TunerR820T
+	TunerR828D
+)
+
+var tunerNames = map[TunerType]string{
+	TunerUnknown: "unknown",
+	TunerE4000:   "E4000",
+	TunerFC0012:  "FC0012",
+	TunerFC0013:  "FC0013",
+	TunerFC2580:  "FC2580",
+	TunerR820T:   "R820T",
+	TunerR828D:   "R828D",
+}
+
+// String returns the name of the tuner chip, such as "R820T".
+func (t TunerType) String() string {
+	if name, ok := tunerNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TunerType(%d)", uint32(t))
+}
+
+// DongleInfo is the header the server sends when a client connects.
+type DongleInfo struct {
+	Tuner     TunerType
+	GainCount int // Number of discrete gain settings the tuner supports.
+}
+
+// Client is a connection to an rtl_tcp server. Read returns the raw cu8 IQ
+// stream, so a Client can be used as the input of an iq.Reader.
+type Client struct {
+	conn net.Conn
+	info DongleInfo
+}
+
+// Dial connects to the rtl_tcp server at addr, such as "localhost:1234".
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient creates a Client on an established connection and reads the
+// dongle information header from it.
+func NewClient(conn net.Conn) (*Client, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, fmt.Errorf("reading rtl_tcp header: %w", err)
+	}
+	if string(header[:4]) != magic {
+		return nil, errors.New("not an rtl_tcp server: bad header magic")
+	}
+	return &Client{
+		conn: conn,
+		info: DongleInfo{
+			Tuner:     TunerType(binary.BigEndian.Uint32(header[4:8])),
+			GainCount: int(binary.BigEndian.Uint32(header[8:12])),
+		},
+	}, nil
+}
+
+// Info returns the dongle information sent by the server.
+func (c *Client) Info() DongleInfo {
+	return c.info
+}
+
+// command sends a command with its parameter to the server.
+func (c *Client) command(cmd byte, param uint32) error {
+	var msg [5]byte
+	msg[0] = cmd
+	binary.BigEndian.PutUint32(msg[1:], param)
+	_, err := c.conn.Write(msg[:])
+	return err
+}
+
+// SetFrequency tunes the dongle to a centre frequency in Hz.
+func (c *Client) SetFrequency(hz float64) error {
+	return c.command(cmdSetFrequency, uint32(math.Round(hz)))
+}
+
+// SetSampleRate sets the IQ sample rate in Hz.
+func (c *Client) SetSampleRate(rate int) error {
+	return c.command(cmdSetSampleRate, uint32(rate))
+}
+
+// SetGainMode selects automatic (false) or manual (true) tuner gain.
+func (c *Client) SetGainMode(manual bool) error {
+	return c.command(cmdSetGainMode, boolParam(manual))
+}
+
+// SetGain sets the manual tuner gain in dB. The tuner rounds it to the
+// nearest supported setting.
+func (c *Client) SetGain(db float64) error {
+	return c.command(cmdSetGain, uint32(int32(math.Round(db*10))))
+}
+
+// SetFrequencyCorrection sets the oscillator correction in parts per million.
+func (c *Client) SetFrequencyCorrection(ppm int) error {
+	return c.command(cmdSetFrequencyCorrection, uint32(int32(ppm)))
+}
+
+// SetAGCMode enables or disables the RTL2832's digital AGC.
+func (c *Client) SetAGCMode(on bool) error {
+	return c.command(cmdSetAGCMode, boolParam(on))
+}
+
+// Read reads raw cu8 IQ samples from the stream.
+func (c *Client) Read(p []byte) (int, error) {
+	return c.conn.Read(p)
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func boolParam(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+package rtltcp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-audio-mini-project/internal/iq"
+)
+
+// command is a command received by the fake server.
+type command struct {
+	cmd   byte
+	param uint32
+}
+
+// fakeServer emulates rtl_tcp: it sends the dongle header, streams the IQ file
+// at path to the first client and records the commands it receives. The
+// commands are delivered once the client disconnects.
+func fakeServer(t *testing.T, path string) (addr string, commands <-chan []command) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan []command, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		header := []byte(magic)
+		header = binary.BigEndian.AppendUint32(header, uint32(TunerR820T))
+		header = binary.BigEndian.AppendUint32(header, 29)
+		conn.Write(header)
+
+		received := make(chan []command, 1)
+		go func() {
+			var cmds []command
+			var msg [5]byte
+			for {
+				if _, err := io.ReadFull(conn, msg[:]); err != nil {
+					received <- cmds
+					return
+				}
+				cmds = append(cmds, command{msg[0], binary.BigEndian.Uint32(msg[1:])})
+			}
+		}()
+
+		file, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		io.Copy(conn, file)
+		conn.(*net.TCPConn).CloseWrite()
+		done <- <-received
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestClient_FakeServer(t *testing.T) {
+	// A synthetic cu8 recording of a quarter-rate complex tone.
+	const numSamples = 50_000
+	raw := make([]byte, 2*numSamples)
+	for i := 0; i < numSamples; i++ {
+		phase := math.Pi / 2 * float64(i)
+		raw[2*i] = byte(math.Round(127.5 + 100*math.Cos(phase)))
+		raw[2*i+1] = byte(math.Round(127.5 + 100*math.Sin(phase)))
+	}
+	path := filepath.Join(t.TempDir(), "tone.cu8")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	addr, commands := fakeServer(t, path)
+	client, err := Dial(addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	if info := client.Info(); info.Tuner != TunerR820T || info.GainCount != 29 {
+		t.Errorf("Unexpected dongle info: %+v", info)
+	}
+
+	for _, err := range []error{
+		client.SetFrequency(98.5e6),
+		client.SetSampleRate(2_048_000),
+		client.SetGainMode(true),
+		client.SetGain(-1.5),
+	} {
+		if err != nil {
+			t.Fatalf("Sending command failed: %v", err)
+		}
+	}
+
+	reader := iq.NewReader(client, iq.CU8)
+	var samples []complex64
+	buf := make([]complex64, 4096)
+	for {
+		n, err := reader.Read(buf)
+		samples = append(samples, buf[:n]...)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+	}
+	client.Close()
+
+	if len(samples) != numSamples {
+		t.Fatalf("Expected %d samples, but got %d", numSamples, len(samples))
+	}
+	want := make([]complex64, numSamples)
+	iq.CU8.Decode(want, raw)
+	for i := range samples {
+		if samples[i] != want[i] {
+			t.Fatalf("Sample %d: expected %v, got %v", i, want[i], samples[i])
+		}
+	}
+
+	expected := []command{
+		{cmdSetFrequency, 98_500_000},
+		{cmdSetSampleRate, 2_048_000},
+		{cmdSetGainMode, 1},
+		{cmdSetGain, uint32(0xFFFFFFF1)}, // -15 tenths of a dB
+	}
+	got := <-commands
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d commands, but got %v", len(expected), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Command %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestNewClient_BadMagic(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+	go server.Write([]byte("HTTP/1.1 200"))
+	if _, err := NewClient(conn); err == nil {
+		t.Errorf("Expected an error for a server without the rtl_tcp header")
+	}
+}